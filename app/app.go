@@ -0,0 +1,138 @@
+package app
+
+import (
+	"fmt"
+	"go_final/auth"
+	"go_final/config"
+	"go_final/handlers"
+	"go_final/mail"
+	"go_final/media"
+	"go_final/middleware"
+	"go_final/migrations"
+	"go_final/models"
+	"go_final/password"
+	"go_final/receipt"
+	"go_final/repositories"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Container holds every layer of the application. All of its fields are
+// exported so tests and alternate binaries can replace any of them before
+// the router is built.
+type Container struct {
+	Config *config.Config
+	Store  repositories.Store
+	Keys   *auth.KeySet
+	Mailer mail.Mailer
+	Blobs  media.BlobStore
+	Health *handlers.HealthState
+
+	UserHandler    handlers.UserHandler
+	ProductHandler handlers.ProductHandler
+	OrderHandler   handlers.OrderHandler
+	CartHandler    handlers.CartHandler
+	APIKeyHandler  handlers.APIKeyHandler
+	PrivacyHandler handlers.PrivacyHandler
+	CatalogHandler handlers.CatalogHandler
+	HealthHandler  handlers.HealthHandler
+
+	CORSMiddleware gin.HandlerFunc
+	// AuthMiddleware accepts user access tokens and API keys,
+	// UserAuthMiddleware only the former for routes acting on a session.
+	AuthMiddleware     gin.HandlerFunc
+	UserAuthMiddleware gin.HandlerFunc
+	// MFAMiddleware follows AuthMiddleware on every route except those a
+	// user needs to set up two-factor authentication.
+	MFAMiddleware gin.HandlerFunc
+}
+
+// New loads the signing keys, the mailer and the password policy and wires
+// the handlers and middleware on top of the given store.
+func New(cfg *config.Config, store repositories.Store) (*Container, error) {
+	keys, err := auth.LoadKeySet(cfg.JWT)
+	if err != nil {
+		return nil, err
+	}
+	mailer, err := mail.New(cfg.Mail)
+	if err != nil {
+		return nil, err
+	}
+	blobs, err := media.New(cfg.Media)
+	if err != nil {
+		return nil, err
+	}
+	policy, err := password.NewPolicy(cfg.Password)
+	if err != nil {
+		return nil, err
+	}
+	for _, role := range cfg.Account.MFARequiredRoles {
+		if !auth.IsKnownRole(models.UserRole(role)) {
+			return nil, fmt.Errorf("MFA_REQUIRED_ROLES names unknown role %q", role)
+		}
+	}
+
+	health := &handlers.HealthState{}
+	return &Container{
+		Config: cfg,
+		Store:  store,
+		Keys:   keys,
+		Mailer: mailer,
+		Blobs:  blobs,
+		Health: health,
+
+		UserHandler:    handlers.NewUserHandler(store.Users(), store.Tokens(), store.LoginThrottles(), store.MFA(), keys, cfg.JWT, mailer, cfg.Account, password.NewHasher(cfg.Password), policy),
+		ProductHandler: handlers.NewProductHandler(store.Products(), blobs, cfg.Media, cfg.Pricing),
+		OrderHandler:   handlers.NewOrderHandler(store.Orders(), store.Users(), receipt.New(cfg.Receipt)),
+		CartHandler:    handlers.NewCartHandler(store.Carts(), store.Orders(), store.Users()),
+		APIKeyHandler:  handlers.NewAPIKeyHandler(store.APIKeys()),
+		PrivacyHandler: handlers.NewPrivacyHandler(store.Users(), store.Orders(), store.Tokens(), store.LoginThrottles()),
+		CatalogHandler: handlers.NewCatalogHandler(store.Catalog(), blobs),
+		HealthHandler:  handlers.NewHealthHandler(health, store),
+
+		CORSMiddleware: middleware.CORS(cfg.CORS.AllowedOrigins),
+		AuthMiddleware: middleware.Authenticate(
+			middleware.BearerJWT(keys, cfg.JWT, store.Tokens()),
+			middleware.APIKey(store.APIKeys()),
+		),
+		UserAuthMiddleware: middleware.AuthorizeJWT(keys, cfg.JWT, store.Tokens()),
+		MFAMiddleware:      middleware.RequireMFA(cfg.Account.MFARequiredRoles),
+	}, nil
+}
+
+// Open opens the configured storage backend and builds the container on top
+// of it. Pending migrations are applied first when cfg.AutoMigrate is set.
+func Open(cfg *config.Config) (*Container, error) {
+	store, err := repositories.NewStore(cfg.Database, cfg.Pricing)
+	if err != nil {
+		return nil, err
+	}
+
+	if sqlStore, ok := store.(repositories.SQLStore); ok && cfg.AutoMigrate {
+		vars, err := migrations.PricingVars(cfg.Pricing)
+		if err != nil {
+			store.Close()
+			return nil, err
+		}
+		migrator, err := migrations.New(sqlStore.DB(), vars)
+		if err != nil {
+			store.Close()
+			return nil, err
+		}
+		if _, err := migrator.Up(); err != nil {
+			store.Close()
+			return nil, err
+		}
+	}
+
+	c, err := New(cfg, store)
+	if err != nil {
+		store.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Container) Close() error {
+	return c.Store.Close()
+}
@@ -0,0 +1,30 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+// apiKeyPrefix marks API keys so that they are recognisable in logs and by
+// secret scanners.
+const apiKeyPrefix = "sdu_"
+
+// GenerateAPIKey returns a new key and the part of it that is kept in clear
+// to tell keys apart.
+func GenerateAPIKey() (key string, prefix string, err error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", err
+	}
+	key = apiKeyPrefix + base64.RawURLEncoding.EncodeToString(secret)
+	return key, key[:len(apiKeyPrefix)+8], nil
+}
+
+// HashAPIKey is how keys are stored and looked up. The keys are random, so a
+// fast hash is enough.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
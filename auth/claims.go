@@ -0,0 +1,170 @@
+package auth
+
+import (
+	"errors"
+	"go_final/config"
+	"go_final/models"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Authentication methods (RFC 8176) recorded in the amr claim.
+const (
+	AMRPassword = "pwd"
+	AMROTP      = "otp"
+)
+
+// Claims are the claims of an access token. The user ID travels in the
+// standard sub claim.
+type Claims struct {
+	Role models.UserRole `json:"role"`
+	AMR  []string        `json:"amr,omitempty"`
+	// IssuedAtMillis is iat in milliseconds, so that tokens issued right
+	// after their user's tokens were revoked are told apart from those
+	// issued right before.
+	IssuedAtMillis int64 `json:"iat_ms,omitempty"`
+	jwt.RegisteredClaims
+}
+
+func NewClaims(cfg config.JWTConfig, userID uint, role models.UserRole, tokenID string, amr []string) Claims {
+	return newClaims(cfg.Issuer, cfg.Audience, cfg.TTL.Duration, userID, role, tokenID, amr)
+}
+
+// NewMFAClaims are the claims of the token handed out after the password
+// step of a sign-in with two-factor authentication. Its audience differs
+// from access tokens, so it is only accepted by the second step.
+func NewMFAClaims(cfg config.JWTConfig, userID uint, role models.UserRole, tokenID string, ttl time.Duration) Claims {
+	return newClaims(cfg.Issuer, MFAAudience(cfg), ttl, userID, role, tokenID, []string{AMRPassword})
+}
+
+func MFAAudience(cfg config.JWTConfig) string {
+	return cfg.Audience + "/mfa"
+}
+
+func newClaims(issuer, audience string, ttl time.Duration, userID uint, role models.UserRole, tokenID string, amr []string) Claims {
+	now := time.Now()
+	return Claims{
+		Role:           role,
+		AMR:            amr,
+		IssuedAtMillis: now.UnixMilli(),
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    issuer,
+			Subject:   strconv.FormatUint(uint64(userID), 10),
+			Audience:  jwt.ClaimStrings{audience},
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			NotBefore: jwt.NewNumericDate(now),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ID:        tokenID,
+		},
+	}
+}
+
+// ParseClaims verifies the signature of tokenString and validates exp, nbf,
+// iss and aud. The sub and jti claims must be present as well.
+func ParseClaims(keys *KeySet, cfg config.JWTConfig, tokenString string) (*Claims, error) {
+	return parseClaims(keys, cfg.Issuer, cfg.Audience, tokenString)
+}
+
+// ParseMFAClaims is ParseClaims for tokens made by NewMFAClaims.
+func ParseMFAClaims(keys *KeySet, cfg config.JWTConfig, tokenString string) (*Claims, error) {
+	return parseClaims(keys, cfg.Issuer, MFAAudience(cfg), tokenString)
+}
+
+func parseClaims(keys *KeySet, issuer, audience, tokenString string) (*Claims, error) {
+	claims := &Claims{}
+	_, err := keys.Parse(tokenString, claims,
+		jwt.WithIssuer(issuer),
+		jwt.WithAudience(audience),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := claims.UserID(); err != nil {
+		return nil, err
+	}
+	if claims.ID == "" {
+		return nil, errors.New("token has no jti claim")
+	}
+	if claims.IssuedAt == nil {
+		return nil, errors.New("token has no iat claim")
+	}
+	return claims, nil
+}
+
+func (c Claims) UserID() (uint, error) {
+	id, err := strconv.ParseUint(c.Subject, 10, 64)
+	if err != nil {
+		return 0, errors.New("token has an invalid sub claim")
+	}
+	return uint(id), nil
+}
+
+// IssuedAtTime is when the token was issued, at millisecond precision when
+// it carries iat_ms and to the second of iat otherwise.
+func (c Claims) IssuedAtTime() time.Time {
+	if c.IssuedAtMillis != 0 {
+		return time.UnixMilli(c.IssuedAtMillis)
+	}
+	return c.IssuedAt.Time
+}
+
+// HasAMR reports whether the token was obtained with method.
+func (c Claims) HasAMR(method string) bool {
+	for _, m := range c.AMR {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+// Principal is the authenticated caller of a request. MFA is set when the
+// caller signed in with a second factor.
+type Principal struct {
+	UserID    uint
+	Role      models.UserRole
+	TokenID   string
+	ExpiresAt time.Time
+	MFA       bool
+	// APIKeyID is set for integrations calling with an API key instead of
+	// a user. They hold exactly the Permissions of the key.
+	APIKeyID    uint
+	Permissions []Permission
+}
+
+// Has reports whether the caller holds permission, through their role or
+// their API key.
+func (p Principal) Has(permission Permission) bool {
+	if p.APIKeyID == 0 {
+		return HasPermission(p.Role, permission)
+	}
+	for _, granted := range p.Permissions {
+		if granted == permission {
+			return true
+		}
+	}
+	return false
+}
+
+const principalKey = "principal"
+
+func SetPrincipal(ctx *gin.Context, principal Principal) {
+	ctx.Set(principalKey, principal)
+}
+
+// CurrentPrincipal returns the caller stored by the authentication
+// middleware, ok is false on routes that are not authenticated.
+func CurrentPrincipal(ctx *gin.Context) (principal Principal, ok bool) {
+	value, exists := ctx.Get(principalKey)
+	if !exists {
+		return Principal{}, false
+	}
+	principal, ok = value.(Principal)
+	return principal, ok
+}
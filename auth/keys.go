@@ -0,0 +1,252 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"go_final/config"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	HS256 = "HS256"
+	RS256 = "RS256"
+	EdDSA = "EdDSA"
+)
+
+// Key is a single signing or verification key identified by its kid.
+// Private is nil for retired keys that are only kept to verify tokens
+// issued before a rotation.
+type Key struct {
+	ID      string
+	Method  jwt.SigningMethod
+	Private crypto.PrivateKey
+	Public  crypto.PublicKey
+}
+
+// KeySet signs tokens with its active key and verifies tokens signed by any
+// of its keys, selected by the kid header.
+type KeySet struct {
+	active *Key
+	keys   map[string]*Key
+}
+
+// LoadKeySet builds the key set described by cfg. With HS256 the shared
+// secret is the only key, otherwise every *.pem file in cfg.KeysDir is
+// loaded with its file name (minus .pem or .pub.pem) as kid.
+func LoadKeySet(cfg config.JWTConfig) (*KeySet, error) {
+	if cfg.Algorithm == HS256 {
+		key := &Key{ID: "hs256", Method: jwt.SigningMethodHS256, Private: []byte(cfg.Secret), Public: []byte(cfg.Secret)}
+		return &KeySet{active: key, keys: map[string]*Key{key.ID: key}}, nil
+	}
+
+	paths, err := filepath.Glob(filepath.Join(cfg.KeysDir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]*Key, len(paths))
+	for _, path := range paths {
+		key, err := loadKey(path)
+		if err != nil {
+			return nil, fmt.Errorf("error while loading %s: %w", path, err)
+		}
+		if err := addKey(keys, key); err != nil {
+			return nil, fmt.Errorf("error while loading %s: %w", path, err)
+		}
+	}
+
+	active, ok := keys[cfg.ActiveKeyID]
+	if !ok {
+		return nil, fmt.Errorf("active key %q not found in %s", cfg.ActiveKeyID, cfg.KeysDir)
+	}
+	if active.Private == nil {
+		return nil, fmt.Errorf("active key %q has no private key", cfg.ActiveKeyID)
+	}
+	if active.Method.Alg() != cfg.Algorithm {
+		return nil, fmt.Errorf("active key %q is a %s key, expected %s", cfg.ActiveKeyID, active.Method.Alg(), cfg.Algorithm)
+	}
+
+	return &KeySet{active: active, keys: keys}, nil
+}
+
+// addKey adds key to keys. A private key and its public key may both be in
+// the directory, as k1.pem and k1.pub.pem, and are merged into one key.
+func addKey(keys map[string]*Key, key *Key) error {
+	existing, ok := keys[key.ID]
+	if !ok {
+		keys[key.ID] = key
+		return nil
+	}
+	if (existing.Private == nil) == (key.Private == nil) {
+		return fmt.Errorf("duplicate key %q", key.ID)
+	}
+	if public, ok := existing.Public.(interface{ Equal(crypto.PublicKey) bool }); !ok || !public.Equal(key.Public) {
+		return fmt.Errorf("public key %q does not match its private key", key.ID)
+	}
+	if key.Private != nil {
+		keys[key.ID] = key
+	}
+	return nil
+}
+
+func loadKey(path string) (*Key, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(content)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	name := filepath.Base(path)
+	key := &Key{ID: strings.TrimSuffix(strings.TrimSuffix(name, ".pem"), ".pub")}
+
+	switch block.Type {
+	case "PRIVATE KEY":
+		key.Private, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key.Private, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		key.Public, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if signer, ok := key.Private.(crypto.Signer); ok {
+		key.Public = signer.Public()
+	}
+	switch key.Public.(type) {
+	case *rsa.PublicKey:
+		key.Method = jwt.SigningMethodRS256
+	case ed25519.PublicKey:
+		key.Method = jwt.SigningMethodEdDSA
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", key.Public)
+	}
+	return key, nil
+}
+
+// Sign signs claims with the active key and sets the kid header.
+func (ks *KeySet) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(ks.active.Method, claims)
+	token.Header["kid"] = ks.active.ID
+	return token.SignedString(ks.active.Private)
+}
+
+// Parse verifies the token with the key named by its kid header. Tokens
+// without a kid are only accepted by an HS256 key set.
+func (ks *KeySet) Parse(tokenString string, claims jwt.Claims, options ...jwt.ParserOption) (*jwt.Token, error) {
+	return jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		if kid == "" && ks.active.Method == jwt.SigningMethodHS256 {
+			kid = ks.active.ID
+		}
+
+		key, ok := ks.keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown signing key: %q", kid)
+		}
+		if token.Method.Alg() != key.Method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return key.Public, nil
+	}, options...)
+}
+
+// JWK is the public part of a key in RFC 7517 format.
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public keys other services need to verify tokens. The
+// HS256 secret is never published, so the set is empty in that mode.
+func (ks *KeySet) JWKS() JWKS {
+	set := JWKS{Keys: []JWK{}}
+	for _, key := range ks.keys {
+		switch public := key.Public.(type) {
+		case *rsa.PublicKey:
+			set.Keys = append(set.Keys, JWK{
+				KeyType:   "RSA",
+				KeyID:     key.ID,
+				Use:       "sig",
+				Algorithm: key.Method.Alg(),
+				N:         base64.RawURLEncoding.EncodeToString(public.N.Bytes()),
+				E:         base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			set.Keys = append(set.Keys, JWK{
+				KeyType:   "OKP",
+				KeyID:     key.ID,
+				Use:       "sig",
+				Algorithm: key.Method.Alg(),
+				Curve:     "Ed25519",
+				X:         base64.RawURLEncoding.EncodeToString(public),
+			})
+		}
+	}
+	sort.Slice(set.Keys, func(i, j int) bool { return set.Keys[i].KeyID < set.Keys[j].KeyID })
+	return set
+}
+
+// GenerateKey writes a new private key for algorithm to dir and returns its
+// kid, which is derived from the current time so keys sort by age.
+func GenerateKey(algorithm, dir string) (string, error) {
+	var private crypto.PrivateKey
+	var err error
+	switch algorithm {
+	case RS256:
+		private, err = rsa.GenerateKey(rand.Reader, 2048)
+	case EdDSA:
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return "", fmt.Errorf("unsupported algorithm %q, use %s or %s", algorithm, RS256, EdDSA)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", err
+	}
+	kid := strings.ToLower(algorithm) + "-" + time.Now().UTC().Format("20060102150405")
+	path := filepath.Join(dir, kid+".pem")
+	content := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	if err := os.WriteFile(path, content, 0o600); err != nil {
+		return "", err
+	}
+	return kid, nil
+}
@@ -0,0 +1,115 @@
+package auth
+
+import (
+	"go_final/models"
+	"sort"
+)
+
+type Permission string
+
+const (
+	ProductsRead  Permission = "products:read"
+	ProductsWrite Permission = "products:write"
+
+	UsersRead   Permission = "users:read"
+	UsersWrite  Permission = "users:write"
+	UsersDelete Permission = "users:delete"
+	UsersRoles  Permission = "users:roles"
+
+	// OrdersCreate covers filling the caller's cart and checking it out.
+	OrdersCreate  Permission = "orders:create"
+	OrdersReadAny Permission = "orders:read:any"
+	// OrdersManage allows acting on orders of other users, e.g. canceling
+	// them on behalf of a customer.
+	OrdersManage Permission = "orders:manage"
+	// OrdersDiscount allows granting a discount on an order.
+	OrdersDiscount Permission = "orders:discount"
+
+	APIKeysManage Permission = "api_keys:manage"
+)
+
+// AdvanceOrder is the permission needed to move an order to status.
+func AdvanceOrder(status models.OrderStatus) Permission {
+	return Permission("orders:advance:" + string(status))
+}
+
+var rolePermissions = map[models.UserRole][]Permission{
+	models.ADMIN_ROLE: {
+		ProductsRead, ProductsWrite,
+		UsersRead, UsersWrite, UsersDelete, UsersRoles,
+		OrdersCreate, OrdersReadAny, OrdersManage, OrdersDiscount,
+		AdvanceOrder(models.ACCEPTED), AdvanceOrder(models.READY), AdvanceOrder(models.OUT),
+		AdvanceOrder(models.DELIVERED), AdvanceOrder(models.CONFIRMED), AdvanceOrder(models.CANCELED),
+		APIKeysManage,
+	},
+	models.CASHIER_ROLE: {
+		ProductsRead,
+		UsersRead,
+		OrdersCreate, OrdersReadAny, OrdersManage, OrdersDiscount,
+		AdvanceOrder(models.ACCEPTED), AdvanceOrder(models.CANCELED),
+	},
+	models.COOK_ROLE: {
+		ProductsRead,
+		OrdersReadAny,
+		AdvanceOrder(models.READY),
+	},
+	models.COURIER_ROLE: {
+		ProductsRead,
+		OrdersReadAny,
+		AdvanceOrder(models.OUT), AdvanceOrder(models.DELIVERED),
+	},
+	models.CUSTOMER_ROLE: {
+		ProductsRead,
+		OrdersCreate,
+		AdvanceOrder(models.CONFIRMED), AdvanceOrder(models.CANCELED),
+	},
+}
+
+var permissionSets = func() map[models.UserRole]map[Permission]bool {
+	sets := make(map[models.UserRole]map[Permission]bool, len(rolePermissions))
+	for role, permissions := range rolePermissions {
+		sets[role] = make(map[Permission]bool, len(permissions))
+		for _, permission := range permissions {
+			sets[role][permission] = true
+		}
+	}
+	return sets
+}()
+
+func HasPermission(role models.UserRole, permission Permission) bool {
+	return permissionSets[role][permission]
+}
+
+// apiKeyPermissions are the permissions integrations such as kitchen
+// displays and POS terminals need: reading the menu and orders and moving
+// orders along. Keys act on behalf of no user, so everything else is left
+// to people signed in with a role.
+var apiKeyPermissions = map[Permission]bool{
+	ProductsRead:                   true,
+	OrdersReadAny:                  true,
+	AdvanceOrder(models.ACCEPTED):  true,
+	AdvanceOrder(models.READY):     true,
+	AdvanceOrder(models.OUT):       true,
+	AdvanceOrder(models.DELIVERED): true,
+}
+
+// IsGrantableToAPIKey reports whether permission may be given to an API key.
+func IsGrantableToAPIKey(permission Permission) bool {
+	return apiKeyPermissions[permission]
+}
+
+func IsKnownRole(role models.UserRole) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// Roles lists every role with its permissions in sorted order.
+func Roles() map[models.UserRole][]Permission {
+	roles := make(map[models.UserRole][]Permission, len(rolePermissions))
+	for role, permissions := range rolePermissions {
+		sorted := append([]Permission(nil), permissions...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+		roles[role] = sorted
+	}
+	return roles
+}
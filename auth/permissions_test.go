@@ -0,0 +1,41 @@
+package auth
+
+import (
+	"go_final/models"
+	"testing"
+)
+
+func TestIsGrantableToAPIKey(t *testing.T) {
+	tests := []struct {
+		permission Permission
+		want       bool
+	}{
+		{ProductsRead, true},
+		{OrdersReadAny, true},
+		{AdvanceOrder(models.ACCEPTED), true},
+		{AdvanceOrder(models.READY), true},
+		{AdvanceOrder(models.OUT), true},
+		{AdvanceOrder(models.DELIVERED), true},
+		// confirming and canceling also need orders:manage for keys
+		{AdvanceOrder(models.CONFIRMED), false},
+		{AdvanceOrder(models.CANCELED), false},
+		{AdvanceOrder(models.PENDING), false},
+		{ProductsWrite, false},
+		{UsersRead, false},
+		{UsersWrite, false},
+		{UsersDelete, false},
+		{UsersRoles, false},
+		{OrdersCreate, false},
+		{OrdersManage, false},
+		{OrdersDiscount, false},
+		{APIKeysManage, false},
+		{"orders:read", false},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.permission), func(t *testing.T) {
+			if got := IsGrantableToAPIKey(tt.permission); got != tt.want {
+				t.Errorf("IsGrantableToAPIKey(%q) = %v, want %v", tt.permission, got, tt.want)
+			}
+		})
+	}
+}
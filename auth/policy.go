@@ -0,0 +1,116 @@
+package auth
+
+import "go_final/models"
+
+type Action string
+
+const (
+	ActionRead   Action = "read"
+	ActionCreate Action = "create"
+	ActionUpdate Action = "update"
+	ActionDelete Action = "delete"
+	// ActionDiscount is granting a discount on an order.
+	ActionDiscount Action = "discount"
+)
+
+// AdvanceTo is the action of moving an order to status.
+func AdvanceTo(status models.OrderStatus) Action {
+	return Action("advance:" + string(status))
+}
+
+type ResourceKind string
+
+const (
+	UserResource    ResourceKind = "user"
+	ProductResource ResourceKind = "product"
+	OrderResource   ResourceKind = "order"
+)
+
+// Resource is what an action is performed on. OwnerID is the user the
+// resource belongs to: the user itself, or the customer who placed the
+// order. Products have no owner.
+type Resource struct {
+	Kind    ResourceKind
+	OwnerID uint
+}
+
+func User(userID uint) Resource {
+	return Resource{Kind: UserResource, OwnerID: userID}
+}
+
+func Product() Resource {
+	return Resource{Kind: ProductResource}
+}
+
+func Order(order models.Order) Resource {
+	return Resource{Kind: OrderResource, OwnerID: order.UserID}
+}
+
+// rule grants an action to callers holding every permission in any, and to
+// the owner of the resource if owner is set and they hold every permission
+// in own.
+type rule struct {
+	any   []Permission
+	owner bool
+	own   []Permission
+}
+
+var policies = map[ResourceKind]map[Action]rule{
+	UserResource: {
+		ActionRead: {any: []Permission{UsersRead}, owner: true},
+		// owners edit themselves through /users/me, which asks for their
+		// password and confirms a new email address
+		ActionUpdate: {any: []Permission{UsersWrite}},
+		ActionDelete: {any: []Permission{UsersDelete}, owner: true},
+	},
+	ProductResource: {
+		ActionRead:   {any: []Permission{ProductsRead}},
+		ActionCreate: {any: []Permission{ProductsWrite}},
+		ActionUpdate: {any: []Permission{ProductsWrite}},
+		ActionDelete: {any: []Permission{ProductsWrite}},
+	},
+	OrderResource: {
+		// orders are placed from the cart and never edited, only moved
+		// along and discounted
+		ActionRead: {any: []Permission{OrdersReadAny}, owner: true},
+		// customers cannot discount their own orders
+		ActionDiscount: {any: []Permission{OrdersDiscount}},
+
+		// the canteen staff moves an order along, whoever placed it
+		AdvanceTo(models.ACCEPTED):  {any: []Permission{AdvanceOrder(models.ACCEPTED)}},
+		AdvanceTo(models.READY):     {any: []Permission{AdvanceOrder(models.READY)}},
+		AdvanceTo(models.OUT):       {any: []Permission{AdvanceOrder(models.OUT)}},
+		AdvanceTo(models.DELIVERED): {any: []Permission{AdvanceOrder(models.DELIVERED)}},
+		// confirming and canceling are customer actions
+		AdvanceTo(models.CONFIRMED): {
+			any:   []Permission{AdvanceOrder(models.CONFIRMED), OrdersManage},
+			owner: true, own: []Permission{AdvanceOrder(models.CONFIRMED)},
+		},
+		AdvanceTo(models.CANCELED): {
+			any:   []Permission{AdvanceOrder(models.CANCELED), OrdersManage},
+			owner: true, own: []Permission{AdvanceOrder(models.CANCELED)},
+		},
+	},
+}
+
+// Can reports whether principal may perform action on resource. Actions
+// without a policy are denied.
+func Can(principal Principal, action Action, resource Resource) bool {
+	r, ok := policies[resource.Kind][action]
+	if !ok {
+		return false
+	}
+	if len(r.any) > 0 && hasAll(principal, r.any) {
+		return true
+	}
+	return r.owner && resource.OwnerID != 0 && resource.OwnerID == principal.UserID && hasAll(principal, r.own)
+}
+
+func hasAll(principal Principal, permissions []Permission) bool {
+	for _, permission := range permissions {
+		if !principal.Has(permission) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,181 @@
+package auth
+
+import (
+	"fmt"
+	"go_final/models"
+	"testing"
+)
+
+var (
+	allKinds   = []ResourceKind{UserResource, ProductResource, OrderResource}
+	allActions = []Action{
+		ActionRead, ActionCreate, ActionUpdate, ActionDelete, ActionDiscount,
+		AdvanceTo(models.PENDING), AdvanceTo(models.ACCEPTED), AdvanceTo(models.READY), AdvanceTo(models.OUT),
+		AdvanceTo(models.DELIVERED), AdvanceTo(models.CONFIRMED), AdvanceTo(models.CANCELED),
+	}
+	allRoles = []models.UserRole{
+		models.ADMIN_ROLE, models.CASHIER_ROLE, models.COOK_ROLE, models.COURIER_ROLE, models.CUSTOMER_ROLE,
+	}
+)
+
+// grant lists the roles allowed an action on resources of other users and
+// the roles allowed it on their own. Owners are allowed whatever anyone is.
+type grant struct {
+	any, own []models.UserRole
+}
+
+var (
+	everyone = allRoles
+	admin    = []models.UserRole{models.ADMIN_ROLE}
+	staff    = []models.UserRole{models.ADMIN_ROLE, models.CASHIER_ROLE}
+)
+
+// roleGrants is the expected policy for users signed in with a role. Kind
+// and action pairs missing from it are denied to everybody.
+var roleGrants = map[ResourceKind]map[Action]grant{
+	UserResource: {
+		ActionRead:   {any: staff, own: everyone},
+		ActionUpdate: {any: admin},
+		ActionDelete: {any: admin, own: everyone},
+	},
+	ProductResource: {
+		ActionRead:   {any: everyone},
+		ActionCreate: {any: admin},
+		ActionUpdate: {any: admin},
+		ActionDelete: {any: admin},
+	},
+	OrderResource: {
+		ActionRead:                  {any: []models.UserRole{models.ADMIN_ROLE, models.CASHIER_ROLE, models.COOK_ROLE, models.COURIER_ROLE}, own: everyone},
+		ActionDiscount:              {any: staff},
+		AdvanceTo(models.ACCEPTED):  {any: staff},
+		AdvanceTo(models.READY):     {any: []models.UserRole{models.ADMIN_ROLE, models.COOK_ROLE}},
+		AdvanceTo(models.OUT):       {any: []models.UserRole{models.ADMIN_ROLE, models.COURIER_ROLE}},
+		AdvanceTo(models.DELIVERED): {any: []models.UserRole{models.ADMIN_ROLE, models.COURIER_ROLE}},
+		// cashiers cancel on behalf of customers but do not confirm
+		AdvanceTo(models.CONFIRMED): {any: admin, own: []models.UserRole{models.ADMIN_ROLE, models.CUSTOMER_ROLE}},
+		AdvanceTo(models.CANCELED):  {any: staff, own: []models.UserRole{models.ADMIN_ROLE, models.CASHIER_ROLE, models.CUSTOMER_ROLE}},
+	},
+}
+
+func hasRole(roles []models.UserRole, role models.UserRole) bool {
+	for _, r := range roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+func TestCanRoles(t *testing.T) {
+	const callerID, otherID = 7, 8
+	for _, kind := range allKinds {
+		for _, action := range allActions {
+			g := roleGrants[kind][action]
+			for _, role := range allRoles {
+				principal := Principal{UserID: callerID, Role: role}
+				tests := []struct {
+					name    string
+					ownerID uint
+					want    bool
+				}{
+					{"other", otherID, hasRole(g.any, role)},
+					{"owner", callerID, hasRole(g.any, role) || hasRole(g.own, role)},
+					{"no owner", 0, hasRole(g.any, role)},
+				}
+				for _, tt := range tests {
+					name := fmt.Sprintf("%s/%s/%s/%s", kind, action, role, tt.name)
+					t.Run(name, func(t *testing.T) {
+						resource := Resource{Kind: kind, OwnerID: tt.ownerID}
+						if got := Can(principal, action, resource); got != tt.want {
+							t.Errorf("Can() = %v, want %v", got, tt.want)
+						}
+					})
+				}
+			}
+		}
+	}
+}
+
+// TestCanUnknownRole checks that a role without permissions is denied
+// everything but what owners need no permission for.
+func TestCanUnknownRole(t *testing.T) {
+	principal := Principal{UserID: 7, Role: models.UserRole("guest")}
+	for _, kind := range allKinds {
+		for _, action := range allActions {
+			if Can(principal, action, Resource{Kind: kind, OwnerID: 8}) {
+				t.Errorf("Can(%s, %s) = true for an unknown role", kind, action)
+			}
+		}
+	}
+}
+
+func TestCanUnknownKind(t *testing.T) {
+	principal := Principal{UserID: 7, Role: models.ADMIN_ROLE}
+	for _, action := range allActions {
+		if Can(principal, action, Resource{Kind: "invoice", OwnerID: 7}) {
+			t.Errorf("Can(invoice, %s) = true", action)
+		}
+	}
+}
+
+func TestCanAPIKeys(t *testing.T) {
+	key := func(permissions ...Permission) Principal {
+		// keys act for no user and their role is ignored
+		return Principal{APIKeyID: 3, Role: models.ADMIN_ROLE, Permissions: permissions}
+	}
+	order := Resource{Kind: OrderResource, OwnerID: 7}
+	tests := []struct {
+		name      string
+		principal Principal
+		action    Action
+		resource  Resource
+		want      bool
+	}{
+		{"no permissions", key(), ActionRead, Product(), false},
+		{"read products", key(ProductsRead), ActionRead, Product(), true},
+		{"read products cannot write", key(ProductsRead), ActionUpdate, Product(), false},
+		{"write products", key(ProductsWrite), ActionCreate, Product(), true},
+		{"read users", key(UsersRead), ActionRead, User(7), true},
+		{"read users cannot update", key(UsersRead), ActionUpdate, User(7), false},
+		{"read orders", key(OrdersReadAny), ActionRead, order, true},
+		{"discount", key(OrdersDiscount), ActionDiscount, order, true},
+		{"accept", key(AdvanceOrder(models.ACCEPTED)), AdvanceTo(models.ACCEPTED), order, true},
+		{"accept cannot deliver", key(AdvanceOrder(models.ACCEPTED)), AdvanceTo(models.DELIVERED), order, false},
+		{"cancel needs manage", key(AdvanceOrder(models.CANCELED)), AdvanceTo(models.CANCELED), order, false},
+		{"cancel with manage", key(AdvanceOrder(models.CANCELED), OrdersManage), AdvanceTo(models.CANCELED), order, true},
+		{"confirm with manage", key(AdvanceOrder(models.CONFIRMED), OrdersManage), AdvanceTo(models.CONFIRMED), order, true},
+		{"owner rule on unowned order", key(AdvanceOrder(models.CONFIRMED)), AdvanceTo(models.CONFIRMED), Resource{Kind: OrderResource}, false},
+		{"owner rule on unowned user", key(), ActionRead, User(0), false},
+		{"unknown action", key(ProductsWrite), ActionDiscount, Product(), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Can(tt.principal, tt.action, tt.resource); got != tt.want {
+				t.Errorf("Can() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCanOwnerIDZero(t *testing.T) {
+	// a caller without a user ID never owns a resource without an owner
+	principal := Principal{Role: models.CUSTOMER_ROLE}
+	tests := []struct {
+		action   Action
+		resource Resource
+	}{
+		{ActionRead, User(0)},
+		{ActionUpdate, User(0)},
+		{ActionDelete, User(0)},
+		{ActionRead, Order(models.Order{})},
+		{AdvanceTo(models.CONFIRMED), Order(models.Order{})},
+		{AdvanceTo(models.CANCELED), Order(models.Order{})},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s/%s", tt.resource.Kind, tt.action), func(t *testing.T) {
+			if Can(principal, tt.action, tt.resource) {
+				t.Error("Can() = true, want false")
+			}
+		})
+	}
+}
@@ -0,0 +1,675 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"go_final/money"
+	"math"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+type Config struct {
+	Server      ServerConfig   `yaml:"server" toml:"server"`
+	Database    DatabaseConfig `yaml:"database" toml:"database"`
+	JWT         JWTConfig      `yaml:"jwt" toml:"jwt"`
+	CORS        CORSConfig     `yaml:"cors" toml:"cors"`
+	Mail        MailConfig     `yaml:"mail" toml:"mail"`
+	Password    PasswordConfig `yaml:"password" toml:"password"`
+	Account     AccountConfig  `yaml:"account" toml:"account"`
+	Media       MediaConfig    `yaml:"media" toml:"media"`
+	Pricing     PricingConfig  `yaml:"pricing" toml:"pricing"`
+	Receipt     ReceiptConfig  `yaml:"receipt" toml:"receipt"`
+	LogLevel    string         `yaml:"log_level" toml:"log_level"`
+	AutoMigrate bool           `yaml:"auto_migrate" toml:"auto_migrate"`
+}
+
+type ServerConfig struct {
+	Address      string   `yaml:"address" toml:"address"`
+	ReadTimeout  Duration `yaml:"read_timeout" toml:"read_timeout"`
+	WriteTimeout Duration `yaml:"write_timeout" toml:"write_timeout"`
+	IdleTimeout  Duration `yaml:"idle_timeout" toml:"idle_timeout"`
+	// DrainDelay is how long the server keeps serving with readiness
+	// reported as draining before it stops accepting connections.
+	DrainDelay Duration `yaml:"drain_delay" toml:"drain_delay"`
+	// ShutdownTimeout bounds how long in-flight requests may take to finish.
+	ShutdownTimeout Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout"`
+	// TrustedProxies may set X-Forwarded-For. Without any the client IP is
+	// the address of the connection.
+	TrustedProxies []string `yaml:"trusted_proxies" toml:"trusted_proxies"`
+}
+
+type DatabaseConfig struct {
+	Driver     string `yaml:"driver" toml:"driver"`
+	Host       string `yaml:"host" toml:"host"`
+	Port       string `yaml:"port" toml:"port"`
+	User       string `yaml:"user" toml:"user"`
+	Password   string `yaml:"password" toml:"password"`
+	Name       string `yaml:"name" toml:"name"`
+	SSLMode    string `yaml:"sslmode" toml:"sslmode"`
+	TimeZone   string `yaml:"timezone" toml:"timezone"`
+	SQLitePath string `yaml:"sqlite_path" toml:"sqlite_path"`
+}
+
+// DSN builds the Postgres connection string.
+func (c DatabaseConfig) DSN() string {
+	return fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=%s TimeZone=%s",
+		c.Host, c.User, c.Password, c.Name, c.Port, c.SSLMode, c.TimeZone)
+}
+
+type JWTConfig struct {
+	// Algorithm is HS256, RS256 or EdDSA. HS256 signs with Secret, the
+	// asymmetric algorithms sign with the ActiveKeyID key from KeysDir.
+	Algorithm   string `yaml:"algorithm" toml:"algorithm"`
+	Secret      string `yaml:"secret" toml:"secret"`
+	KeysDir     string `yaml:"keys_dir" toml:"keys_dir"`
+	ActiveKeyID string `yaml:"active_key_id" toml:"active_key_id"`
+	Issuer      string `yaml:"issuer" toml:"issuer"`
+	Audience    string `yaml:"audience" toml:"audience"`
+	// TTL is the lifetime of access tokens, keep it short since they can
+	// only be revoked through the revocation list.
+	TTL        Duration `yaml:"ttl" toml:"ttl"`
+	RefreshTTL Duration `yaml:"refresh_ttl" toml:"refresh_ttl"`
+}
+
+type CORSConfig struct {
+	AllowedOrigins []string `yaml:"allowed_origins" toml:"allowed_origins"`
+}
+
+type MailConfig struct {
+	// Driver is smtp, or file to write every message to OutboxDir (or the
+	// log when OutboxDir is empty) during local development.
+	Driver       string `yaml:"driver" toml:"driver"`
+	From         string `yaml:"from" toml:"from"`
+	SMTPHost     string `yaml:"smtp_host" toml:"smtp_host"`
+	SMTPPort     string `yaml:"smtp_port" toml:"smtp_port"`
+	SMTPUser     string `yaml:"smtp_user" toml:"smtp_user"`
+	SMTPPassword string `yaml:"smtp_password" toml:"smtp_password"`
+	OutboxDir    string `yaml:"outbox_dir" toml:"outbox_dir"`
+}
+
+type AccountConfig struct {
+	// BaseURL is the address of the web client, links sent by email point
+	// to its /verify-email and /reset-password pages.
+	BaseURL          string   `yaml:"base_url" toml:"base_url"`
+	VerificationTTL  Duration `yaml:"verification_ttl" toml:"verification_ttl"`
+	PasswordResetTTL Duration `yaml:"password_reset_ttl" toml:"password_reset_ttl"`
+	// An email address or client IP is locked out after this many failed
+	// sign-ins, for LoginLockout doubled with every further failure up to
+	// LoginMaxLockout. Failures older than LoginMaxLockout are forgotten.
+	LoginMaxFailures   int      `yaml:"login_max_failures" toml:"login_max_failures"`
+	LoginIPMaxFailures int      `yaml:"login_ip_max_failures" toml:"login_ip_max_failures"`
+	LoginLockout       Duration `yaml:"login_lockout" toml:"login_lockout"`
+	LoginMaxLockout    Duration `yaml:"login_max_lockout" toml:"login_max_lockout"`
+	// MFAIssuer names the service in authenticator apps. MFAPendingTTL is
+	// how long the second step of a two-factor sign-in may take, and users
+	// of MFARequiredRoles cannot use the API before enabling two-factor
+	// authentication.
+	MFAIssuer        string   `yaml:"mfa_issuer" toml:"mfa_issuer"`
+	MFAPendingTTL    Duration `yaml:"mfa_pending_ttl" toml:"mfa_pending_ttl"`
+	MFARequiredRoles []string `yaml:"mfa_required_roles" toml:"mfa_required_roles"`
+}
+
+type MediaConfig struct {
+	// Driver stores uploaded images. The local driver keeps them under Dir
+	// and serves them at the path of BaseURL.
+	Driver  string `yaml:"driver" toml:"driver"`
+	Dir     string `yaml:"dir" toml:"dir"`
+	BaseURL string `yaml:"base_url" toml:"base_url"`
+	// MaxUploadSize is in bytes. Every upload is scaled down to each of
+	// ThumbnailWidths, in pixels.
+	MaxUploadSize   int   `yaml:"max_upload_size" toml:"max_upload_size"`
+	ThumbnailWidths []int `yaml:"thumbnail_widths" toml:"thumbnail_widths"`
+}
+
+type PricingConfig struct {
+	// Currency is the ISO 4217 code every price is in. Prices sent as bare
+	// numbers of minor units are taken to be in it.
+	Currency string `yaml:"currency" toml:"currency"`
+	// VATRate is a percentage with at most two decimals, zero when the
+	// canteen does not charge VAT. VATIncluded prices already contain it,
+	// otherwise it is added on top of the order.
+	VATRate     float64 `yaml:"vat_rate" toml:"vat_rate"`
+	VATIncluded bool    `yaml:"vat_included" toml:"vat_included"`
+}
+
+// VATBasisPoints is VATRate in hundredths of a percent.
+func (p PricingConfig) VATBasisPoints() int64 {
+	return int64(math.Round(p.VATRate * 100))
+}
+
+type ReceiptConfig struct {
+	// Title heads every receipt. Width is in characters, 42 fits an 80 mm
+	// roll and 32 a 58 mm one.
+	Title string `yaml:"title" toml:"title"`
+	Width int    `yaml:"width" toml:"width"`
+	// Font is a monospaced TrueType font for PDF receipts. Without one
+	// they fall back to Courier, which has no Cyrillic letters.
+	Font string `yaml:"font" toml:"font"`
+}
+
+type PasswordConfig struct {
+	// Algorithm hashes new passwords, bcrypt or argon2id. Hashes made with
+	// another algorithm or other parameters are replaced on sign-in.
+	Algorithm         string `yaml:"algorithm" toml:"algorithm"`
+	BcryptCost        int    `yaml:"bcrypt_cost" toml:"bcrypt_cost"`
+	Argon2Memory      int    `yaml:"argon2_memory" toml:"argon2_memory"` // KiB
+	Argon2Iterations  int    `yaml:"argon2_iterations" toml:"argon2_iterations"`
+	Argon2Parallelism int    `yaml:"argon2_parallelism" toml:"argon2_parallelism"`
+
+	MinLength        int      `yaml:"min_length" toml:"min_length"`
+	MaxLength        int      `yaml:"max_length" toml:"max_length"`
+	RequiredClasses  []string `yaml:"required_classes" toml:"required_classes"`
+	BreachedListPath string   `yaml:"breached_list" toml:"breached_list"`
+}
+
+// Duration lets durations be written as "15m" or "24h" in config files.
+type Duration struct {
+	time.Duration
+}
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	duration, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	d.Duration = duration
+	return nil
+}
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Address:         ":8080",
+			ReadTimeout:     Duration{15 * time.Second},
+			WriteTimeout:    Duration{15 * time.Second},
+			IdleTimeout:     Duration{60 * time.Second},
+			ShutdownTimeout: Duration{30 * time.Second},
+		},
+		Database: DatabaseConfig{
+			Driver:     "postgres",
+			Port:       "5432",
+			SSLMode:    "disable",
+			TimeZone:   "Asia/Shanghai",
+			SQLitePath: "canteen.db",
+		},
+		JWT: JWTConfig{
+			Algorithm:  "HS256",
+			Issuer:     "sdu-canteen",
+			Audience:   "sdu-canteen-api",
+			TTL:        Duration{15 * time.Minute},
+			RefreshTTL: Duration{30 * 24 * time.Hour},
+		},
+		Mail: MailConfig{
+			Driver:   "file",
+			From:     "SDU Canteen <no-reply@canteen.local>",
+			SMTPPort: "587",
+		},
+		Password: PasswordConfig{
+			Algorithm:         "bcrypt",
+			BcryptCost:        10,
+			Argon2Memory:      64 * 1024,
+			Argon2Iterations:  3,
+			Argon2Parallelism: 2,
+			MinLength:         8,
+			MaxLength:         72,
+			RequiredClasses:   []string{"lower", "upper", "digit"},
+		},
+		Account: AccountConfig{
+			BaseURL:          "http://localhost:8080",
+			VerificationTTL:  Duration{24 * time.Hour},
+			PasswordResetTTL: Duration{time.Hour},
+
+			LoginMaxFailures:   5,
+			LoginIPMaxFailures: 20,
+			LoginLockout:       Duration{time.Minute},
+			LoginMaxLockout:    Duration{time.Hour},
+
+			MFAIssuer:     "SDU Canteen",
+			MFAPendingTTL: Duration{5 * time.Minute},
+		},
+		Media: MediaConfig{
+			Driver:          "local",
+			Dir:             "uploads",
+			BaseURL:         "/media",
+			MaxUploadSize:   5 << 20,
+			ThumbnailWidths: []int{160, 320, 640},
+		},
+		Pricing: PricingConfig{
+			Currency:    "KZT",
+			VATIncluded: true,
+		},
+		Receipt: ReceiptConfig{
+			Title: "SDU Canteen",
+			Width: 42,
+			Font:  "/usr/share/fonts/truetype/dejavu/DejaVuSansMono.ttf",
+		},
+		LogLevel: "info",
+	}
+}
+
+// Load builds the configuration from, in increasing priority: defaults, the
+// YAML or TOML file named by CONFIG_FILE, the .env file and the process
+// environment. The result is validated before it is returned.
+func Load() (*Config, error) {
+	cfg := Default()
+
+	if err := godotenv.Load(); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("error while loading .env file: %w", err)
+	}
+
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := cfg.loadEnv(); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func (c *Config) loadFile(path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("error while reading config file: %w", err)
+	}
+
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(content, c)
+	case ".toml":
+		err = toml.Unmarshal(content, c)
+	default:
+		return fmt.Errorf("unsupported config file format %q, use .yaml or .toml", ext)
+	}
+	if err != nil {
+		return fmt.Errorf("error while parsing %s: %w", path, err)
+	}
+	return nil
+}
+
+func (c *Config) loadEnv() error {
+	setString(&c.Server.Address, "LISTEN_ADDR")
+	durations := map[string]*Duration{
+		"SERVER_READ_TIMEOUT":     &c.Server.ReadTimeout,
+		"SERVER_WRITE_TIMEOUT":    &c.Server.WriteTimeout,
+		"SERVER_IDLE_TIMEOUT":     &c.Server.IdleTimeout,
+		"SERVER_DRAIN_DELAY":      &c.Server.DrainDelay,
+		"SERVER_SHUTDOWN_TIMEOUT": &c.Server.ShutdownTimeout,
+	}
+	for key, dst := range durations {
+		if err := setDuration(dst, key); err != nil {
+			return err
+		}
+	}
+
+	if proxies, ok := os.LookupEnv("TRUSTED_PROXIES"); ok {
+		c.Server.TrustedProxies = splitList(proxies)
+	}
+
+	setString(&c.Database.Driver, "DB_DRIVER")
+	setString(&c.Database.Host, "HOST")
+	setString(&c.Database.Port, "PORT")
+	setString(&c.Database.User, "POSTGRES_USER")
+	setString(&c.Database.Password, "POSTGRES_PASSWORD")
+	setString(&c.Database.Name, "POSTGRES_DB")
+	setString(&c.Database.SSLMode, "DB_SSLMODE")
+	setString(&c.Database.TimeZone, "DB_TIMEZONE")
+	setString(&c.Database.SQLitePath, "SQLITE_PATH")
+
+	setString(&c.JWT.Algorithm, "JWT_ALGORITHM")
+	setString(&c.JWT.Secret, "JWT_SECRET")
+	setString(&c.JWT.KeysDir, "JWT_KEYS_DIR")
+	setString(&c.JWT.ActiveKeyID, "JWT_ACTIVE_KEY_ID")
+	setString(&c.JWT.Issuer, "JWT_ISSUER")
+	setString(&c.JWT.Audience, "JWT_AUDIENCE")
+	if err := setDuration(&c.JWT.TTL, "JWT_TTL"); err != nil {
+		return err
+	}
+	if err := setDuration(&c.JWT.RefreshTTL, "JWT_REFRESH_TTL"); err != nil {
+		return err
+	}
+
+	if origins, ok := os.LookupEnv("CORS_ALLOWED_ORIGINS"); ok {
+		c.CORS.AllowedOrigins = splitList(origins)
+	}
+
+	setString(&c.Mail.Driver, "MAIL_DRIVER")
+	setString(&c.Mail.From, "MAIL_FROM")
+	setString(&c.Mail.SMTPHost, "SMTP_HOST")
+	setString(&c.Mail.SMTPPort, "SMTP_PORT")
+	setString(&c.Mail.SMTPUser, "SMTP_USER")
+	setString(&c.Mail.SMTPPassword, "SMTP_PASSWORD")
+	setString(&c.Mail.OutboxDir, "MAIL_OUTBOX_DIR")
+
+	setString(&c.Password.Algorithm, "PASSWORD_HASH")
+	ints := map[string]*int{
+		"BCRYPT_COST":         &c.Password.BcryptCost,
+		"ARGON2_MEMORY":       &c.Password.Argon2Memory,
+		"ARGON2_ITERATIONS":   &c.Password.Argon2Iterations,
+		"ARGON2_PARALLELISM":  &c.Password.Argon2Parallelism,
+		"PASSWORD_MIN_LENGTH": &c.Password.MinLength,
+		"PASSWORD_MAX_LENGTH": &c.Password.MaxLength,
+	}
+	for key, dst := range ints {
+		if err := setInt(dst, key); err != nil {
+			return err
+		}
+	}
+	if required, ok := os.LookupEnv("PASSWORD_REQUIRED_CLASSES"); ok {
+		c.Password.RequiredClasses = splitList(required)
+	}
+	setString(&c.Password.BreachedListPath, "PASSWORD_BREACHED_LIST")
+
+	setString(&c.Account.BaseURL, "APP_BASE_URL")
+	if err := setDuration(&c.Account.VerificationTTL, "EMAIL_VERIFICATION_TTL"); err != nil {
+		return err
+	}
+	if err := setDuration(&c.Account.PasswordResetTTL, "PASSWORD_RESET_TTL"); err != nil {
+		return err
+	}
+	if err := setInt(&c.Account.LoginMaxFailures, "LOGIN_MAX_FAILURES"); err != nil {
+		return err
+	}
+	if err := setInt(&c.Account.LoginIPMaxFailures, "LOGIN_IP_MAX_FAILURES"); err != nil {
+		return err
+	}
+	if err := setDuration(&c.Account.LoginLockout, "LOGIN_LOCKOUT"); err != nil {
+		return err
+	}
+	if err := setDuration(&c.Account.LoginMaxLockout, "LOGIN_MAX_LOCKOUT"); err != nil {
+		return err
+	}
+	setString(&c.Account.MFAIssuer, "MFA_ISSUER")
+	if err := setDuration(&c.Account.MFAPendingTTL, "MFA_PENDING_TTL"); err != nil {
+		return err
+	}
+	if roles, ok := os.LookupEnv("MFA_REQUIRED_ROLES"); ok {
+		c.Account.MFARequiredRoles = splitList(roles)
+	}
+
+	setString(&c.Media.Driver, "MEDIA_DRIVER")
+	setString(&c.Media.Dir, "MEDIA_DIR")
+	setString(&c.Media.BaseURL, "MEDIA_BASE_URL")
+	if err := setInt(&c.Media.MaxUploadSize, "MEDIA_MAX_UPLOAD_SIZE"); err != nil {
+		return err
+	}
+	if widths, ok := os.LookupEnv("MEDIA_THUMBNAIL_WIDTHS"); ok {
+		c.Media.ThumbnailWidths = nil
+		for _, width := range splitList(widths) {
+			parsed, err := strconv.Atoi(width)
+			if err != nil {
+				return fmt.Errorf("MEDIA_THUMBNAIL_WIDTHS must list integers, got %q", width)
+			}
+			c.Media.ThumbnailWidths = append(c.Media.ThumbnailWidths, parsed)
+		}
+	}
+
+	setString(&c.Pricing.Currency, "CURRENCY")
+	if err := setFloat(&c.Pricing.VATRate, "VAT_RATE"); err != nil {
+		return err
+	}
+	if err := setBool(&c.Pricing.VATIncluded, "VAT_INCLUDED"); err != nil {
+		return err
+	}
+
+	setString(&c.Receipt.Title, "RECEIPT_TITLE")
+	if err := setInt(&c.Receipt.Width, "RECEIPT_WIDTH"); err != nil {
+		return err
+	}
+	setString(&c.Receipt.Font, "RECEIPT_FONT")
+
+	setString(&c.LogLevel, "LOG_LEVEL")
+	return setBool(&c.AutoMigrate, "AUTO_MIGRATE")
+}
+
+func setString(dst *string, key string) {
+	if value, ok := os.LookupEnv(key); ok {
+		*dst = value
+	}
+}
+
+func setBool(dst *bool, key string) error {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return nil
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return fmt.Errorf("%s must be a boolean, got %q", key, value)
+	}
+	*dst = parsed
+	return nil
+}
+
+func setInt(dst *int, key string) error {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return nil
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("%s must be an integer, got %q", key, value)
+	}
+	*dst = parsed
+	return nil
+}
+
+func setFloat(dst *float64, key string) error {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return nil
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return fmt.Errorf("%s must be a number, got %q", key, value)
+	}
+	*dst = parsed
+	return nil
+}
+
+func setDuration(dst *Duration, key string) error {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return nil
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return fmt.Errorf("%s must be a duration like 15m or 24h, got %q", key, value)
+	}
+	dst.Duration = parsed
+	return nil
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// Validate reports every missing or malformed setting at once.
+func (c *Config) Validate() error {
+	var errs []error
+
+	if c.Server.Address == "" {
+		errs = append(errs, errors.New("LISTEN_ADDR must not be empty"))
+	}
+	if c.Server.ReadTimeout.Duration <= 0 || c.Server.WriteTimeout.Duration <= 0 || c.Server.IdleTimeout.Duration <= 0 {
+		errs = append(errs, errors.New("SERVER_READ_TIMEOUT, SERVER_WRITE_TIMEOUT and SERVER_IDLE_TIMEOUT must be positive"))
+	}
+	if c.Server.ShutdownTimeout.Duration <= 0 {
+		errs = append(errs, errors.New("SERVER_SHUTDOWN_TIMEOUT must be positive"))
+	}
+	if c.Server.DrainDelay.Duration < 0 {
+		errs = append(errs, errors.New("SERVER_DRAIN_DELAY must not be negative"))
+	}
+	for _, proxy := range c.Server.TrustedProxies {
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			errs = append(errs, fmt.Errorf("TRUSTED_PROXIES must list IPs or CIDRs, got %q", proxy))
+		}
+	}
+
+	switch c.Database.Driver {
+	case "postgres":
+		if c.Database.Host == "" {
+			errs = append(errs, errors.New("HOST must be set for the postgres driver"))
+		}
+		if c.Database.User == "" {
+			errs = append(errs, errors.New("POSTGRES_USER must be set for the postgres driver"))
+		}
+		if c.Database.Name == "" {
+			errs = append(errs, errors.New("POSTGRES_DB must be set for the postgres driver"))
+		}
+	case "sqlite":
+		if c.Database.SQLitePath == "" {
+			errs = append(errs, errors.New("SQLITE_PATH must be set for the sqlite driver"))
+		}
+	case "memory":
+	default:
+		errs = append(errs, fmt.Errorf("DB_DRIVER must be one of postgres, sqlite, memory, got %q", c.Database.Driver))
+	}
+
+	switch c.JWT.Algorithm {
+	case "HS256":
+		if c.JWT.Secret == "" {
+			errs = append(errs, errors.New("JWT_SECRET must be set, tokens cannot be signed with an empty key"))
+		}
+	case "RS256", "EdDSA":
+		if c.JWT.KeysDir == "" || c.JWT.ActiveKeyID == "" {
+			errs = append(errs, fmt.Errorf("JWT_KEYS_DIR and JWT_ACTIVE_KEY_ID must be set for %s", c.JWT.Algorithm))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("JWT_ALGORITHM must be one of HS256, RS256, EdDSA, got %q", c.JWT.Algorithm))
+	}
+	if c.JWT.Issuer == "" || c.JWT.Audience == "" {
+		errs = append(errs, errors.New("JWT_ISSUER and JWT_AUDIENCE must not be empty"))
+	}
+	if c.JWT.TTL.Duration <= 0 {
+		errs = append(errs, errors.New("JWT_TTL must be positive"))
+	}
+	if c.JWT.RefreshTTL.Duration <= c.JWT.TTL.Duration {
+		errs = append(errs, errors.New("JWT_REFRESH_TTL must be longer than JWT_TTL"))
+	}
+
+	switch c.Mail.Driver {
+	case "smtp":
+		if c.Mail.SMTPHost == "" || c.Mail.SMTPPort == "" {
+			errs = append(errs, errors.New("SMTP_HOST and SMTP_PORT must be set for the smtp mail driver"))
+		}
+	case "file":
+	default:
+		errs = append(errs, fmt.Errorf("MAIL_DRIVER must be one of smtp, file, got %q", c.Mail.Driver))
+	}
+	if c.Mail.From == "" {
+		errs = append(errs, errors.New("MAIL_FROM must not be empty"))
+	}
+	switch c.Password.Algorithm {
+	case "bcrypt":
+		// bcrypt.MinCost and bcrypt.MaxCost
+		if c.Password.BcryptCost < 4 || c.Password.BcryptCost > 31 {
+			errs = append(errs, errors.New("BCRYPT_COST must be between 4 and 31"))
+		}
+		if c.Password.MaxLength > 72 {
+			errs = append(errs, errors.New("PASSWORD_MAX_LENGTH must not exceed 72 with bcrypt, longer passwords are truncated"))
+		}
+	case "argon2id":
+		if c.Password.Argon2Memory < 8*c.Password.Argon2Parallelism || c.Password.Argon2Iterations < 1 ||
+			c.Password.Argon2Parallelism < 1 || c.Password.Argon2Parallelism > 255 {
+			errs = append(errs, errors.New("ARGON2_ITERATIONS must be positive, ARGON2_PARALLELISM between 1 and 255 and ARGON2_MEMORY at least 8 KiB per thread"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("PASSWORD_HASH must be one of bcrypt, argon2id, got %q", c.Password.Algorithm))
+	}
+	if c.Password.MinLength < 1 || c.Password.MaxLength < c.Password.MinLength {
+		errs = append(errs, errors.New("PASSWORD_MIN_LENGTH must be positive and not above PASSWORD_MAX_LENGTH"))
+	}
+	for _, class := range c.Password.RequiredClasses {
+		switch class {
+		case "lower", "upper", "digit", "symbol":
+		default:
+			errs = append(errs, fmt.Errorf("PASSWORD_REQUIRED_CLASSES must list lower, upper, digit or symbol, got %q", class))
+		}
+	}
+
+	if c.Account.BaseURL == "" {
+		errs = append(errs, errors.New("APP_BASE_URL must not be empty"))
+	}
+	if c.Account.VerificationTTL.Duration <= 0 || c.Account.PasswordResetTTL.Duration <= 0 {
+		errs = append(errs, errors.New("EMAIL_VERIFICATION_TTL and PASSWORD_RESET_TTL must be positive"))
+	}
+	if c.Account.LoginMaxFailures <= 0 || c.Account.LoginIPMaxFailures <= 0 {
+		errs = append(errs, errors.New("LOGIN_MAX_FAILURES and LOGIN_IP_MAX_FAILURES must be positive"))
+	}
+	if c.Account.LoginLockout.Duration <= 0 || c.Account.LoginMaxLockout.Duration < c.Account.LoginLockout.Duration {
+		errs = append(errs, errors.New("LOGIN_LOCKOUT must be positive and no longer than LOGIN_MAX_LOCKOUT"))
+	}
+	if c.Account.MFAIssuer == "" {
+		errs = append(errs, errors.New("MFA_ISSUER must not be empty"))
+	}
+	if c.Account.MFAPendingTTL.Duration <= 0 {
+		errs = append(errs, errors.New("MFA_PENDING_TTL must be positive"))
+	}
+
+	switch c.Media.Driver {
+	case "local":
+		if c.Media.Dir == "" {
+			errs = append(errs, errors.New("MEDIA_DIR must be set for the local media driver"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("MEDIA_DRIVER must be local, got %q", c.Media.Driver))
+	}
+	if c.Media.BaseURL == "" {
+		errs = append(errs, errors.New("MEDIA_BASE_URL must not be empty"))
+	}
+	if c.Media.MaxUploadSize <= 0 {
+		errs = append(errs, errors.New("MEDIA_MAX_UPLOAD_SIZE must be positive"))
+	}
+	for _, width := range c.Media.ThumbnailWidths {
+		if width < 16 || width > 4096 {
+			errs = append(errs, fmt.Errorf("MEDIA_THUMBNAIL_WIDTHS must be between 16 and 4096, got %d", width))
+		}
+	}
+
+	if !money.ValidCurrency(c.Pricing.Currency) {
+		errs = append(errs, fmt.Errorf("CURRENCY must be a supported ISO 4217 code, got %q", c.Pricing.Currency))
+	}
+	if c.Pricing.VATRate < 0 || c.Pricing.VATRate >= 100 || math.Abs(c.Pricing.VATRate*100-math.Round(c.Pricing.VATRate*100)) > 1e-9 {
+		errs = append(errs, fmt.Errorf("VAT_RATE must be a percentage below 100 with at most two decimals, got %v", c.Pricing.VATRate))
+	}
+	if c.Receipt.Width < 24 || c.Receipt.Width > 80 {
+		errs = append(errs, fmt.Errorf("RECEIPT_WIDTH must be between 24 and 80, got %d", c.Receipt.Width))
+	}
+
+	switch c.LogLevel {
+	case "debug", "info", "warn", "error":
+	default:
+		errs = append(errs, fmt.Errorf("LOG_LEVEL must be one of debug, info, warn, error, got %q", c.LogLevel))
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid configuration:\n%w", errors.Join(errs...))
+	}
+	return nil
+}
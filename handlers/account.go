@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"go_final/mail"
+	"go_final/models"
+	"go_final/repositories"
+	"gorm.io/gorm"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// sendUserToken creates a single-use token for purpose and mails user.Email
+// a link to page of the web client carrying it.
+func (h *userHandler) sendUserToken(user models.User, purpose models.UserTokenPurpose, ttl time.Duration, page, subject, text string) error {
+	token, err := randomToken(32)
+	if err != nil {
+		return err
+	}
+	_, err = h.tokens.CreateUserToken(models.UserToken{
+		UserID:    user.ID,
+		Purpose:   purpose,
+		TokenHash: hashToken(token),
+		Email:     user.Email,
+		ExpiresAt: time.Now().Add(ttl),
+	})
+	if err != nil {
+		return err
+	}
+
+	link := strings.TrimRight(h.account.BaseURL, "/") + "/" + page + "?token=" + url.QueryEscape(token)
+	return h.mailer.Send(mail.Message{
+		To:      user.Email,
+		Subject: subject,
+		Body: fmt.Sprintf("Hello %s,\n\n%s\n\n%s\n\nThe link expires in %s. If you did not ask for this, ignore this email.\n",
+			user.Name, text, link, formatTTL(ttl)),
+	})
+}
+
+func formatTTL(ttl time.Duration) string {
+	if ttl >= time.Hour && ttl%time.Hour == 0 {
+		return fmt.Sprintf("%d hour(s)", ttl/time.Hour)
+	}
+	return fmt.Sprintf("%d minute(s)", ttl/time.Minute)
+}
+
+func (h *userHandler) sendVerification(user models.User) error {
+	return h.sendUserToken(user, models.VerifyEmailPurpose, h.account.VerificationTTL.Duration,
+		"verify-email", "Confirm your email address",
+		"Please confirm your email address to activate your SDU Canteen account:")
+}
+
+func (h *userHandler) VerifyEmail(ctx *gin.Context) {
+	var input models.VerifyEmailRequest
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	token, err := h.tokens.ConsumeUserToken(models.VerifyEmailPurpose, hashToken(input.Token))
+	if errors.Is(err, repositories.ErrUserTokenInvalid) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Verification link is invalid or has expired"})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	now := time.Now()
+	if _, err := h.repo.UpdateUser(models.User{Model: gorm.Model{ID: token.UserID}, EmailVerifiedAt: &now}); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Email address verified"})
+}
+
+// ResendVerification answers the same way and just as fast whether or not
+// the address is registered, so it cannot be used to probe for accounts. The
+// address is looked up and mailed after answering.
+func (h *userHandler) ResendVerification(ctx *gin.Context) {
+	var input models.EmailRequest
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	go func() {
+		user, err := h.repo.GetByEmail(input.Email)
+		if err == nil && user.EmailVerifiedAt == nil {
+			if err := h.sendVerification(user); err != nil {
+				log.Printf("Error while sending verification email to user %d: %v", user.ID, err)
+			}
+		}
+	}()
+
+	ctx.JSON(http.StatusAccepted, gin.H{"message": "If the address needs verification, a new link has been sent"})
+}
+
+// ForgotPassword answers the same way and just as fast whether or not the
+// address is registered, like ResendVerification.
+func (h *userHandler) ForgotPassword(ctx *gin.Context) {
+	var input models.EmailRequest
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	go func() {
+		user, err := h.repo.GetByEmail(input.Email)
+		if err != nil {
+			return
+		}
+		err = h.sendUserToken(user, models.ResetPasswordPurpose, h.account.PasswordResetTTL.Duration,
+			"reset-password", "Reset your password",
+			"Someone asked to reset the password of your SDU Canteen account. Use this link to choose a new one:")
+		if err != nil {
+			log.Printf("Error while sending password reset email to user %d: %v", user.ID, err)
+		}
+	}()
+
+	ctx.JSON(http.StatusAccepted, gin.H{"message": "If the address is registered, a reset link has been sent"})
+}
+
+func (h *userHandler) ResetPassword(ctx *gin.Context) {
+	var input models.ResetPasswordRequest
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// checked first so that a rejected password does not use up the link
+	if !h.validatePassword(ctx, input.Password) {
+		return
+	}
+
+	token, err := h.tokens.ConsumeUserToken(models.ResetPasswordPurpose, hashToken(input.Token))
+	if errors.Is(err, repositories.ErrUserTokenInvalid) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Reset link is invalid or has expired"})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// the link was delivered to the mailbox, which proves the address too
+	hash, err := h.passwords.Hash(input.Password)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	now := time.Now()
+	user := models.User{Model: gorm.Model{ID: token.UserID}, Password: hash, EmailVerifiedAt: &now}
+	if _, err := h.repo.UpdateUser(user); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.tokens.RevokeUserTokens(token.UserID); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Password has been reset"})
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"go_final/auth"
+	"go_final/models"
+	"go_final/repositories"
+	"gorm.io/gorm"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type APIKeyHandler interface {
+	GetAPIKeys(*gin.Context)
+	CreateAPIKey(*gin.Context)
+	RevokeAPIKey(*gin.Context)
+}
+
+type apiKeyHandler struct {
+	repo repositories.APIKeyRepository
+}
+
+func NewAPIKeyHandler(repo repositories.APIKeyRepository) APIKeyHandler {
+	return &apiKeyHandler{
+		repo: repo,
+	}
+}
+
+func (h *apiKeyHandler) GetAPIKeys(ctx *gin.Context) {
+	keys, err := h.repo.GetAPIKeys()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, keys)
+}
+
+// CreateAPIKey answers with the key itself, which is not stored and cannot
+// be shown again.
+func (h *apiKeyHandler) CreateAPIKey(ctx *gin.Context) {
+	var input models.APIKeyCreate
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	for _, permission := range input.Permissions {
+		if !auth.IsGrantableToAPIKey(auth.Permission(permission)) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Permission cannot be granted to an API key: " + permission})
+			return
+		}
+	}
+	if input.ExpiresAt != nil && !input.ExpiresAt.After(time.Now()) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "expires_at must be in the future"})
+		return
+	}
+	principal, ok := currentPrincipal(ctx)
+	if !ok {
+		return
+	}
+
+	key, prefix, err := auth.GenerateAPIKey()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	stored, err := h.repo.CreateAPIKey(models.APIKey{
+		Name:        input.Name,
+		Prefix:      prefix,
+		KeyHash:     auth.HashAPIKey(key),
+		Permissions: input.Permissions,
+		CreatedBy:   principal.UserID,
+		ExpiresAt:   input.ExpiresAt,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"key": key, "api_key": stored})
+}
+
+func (h *apiKeyHandler) RevokeAPIKey(ctx *gin.Context) {
+	intID, err := strconv.Atoi(ctx.Param("api_key_id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err = h.repo.RevokeAPIKey(uint(intID))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "No such active API key"})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
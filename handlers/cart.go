@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"go_final/models"
+	"go_final/repositories"
+	"gorm.io/gorm"
+	"net/http"
+)
+
+// CartHandler serves the cart of the caller. Nothing in a cart is reserved
+// until it is checked out into an order.
+type CartHandler interface {
+	GetCart(*gin.Context)
+	AddItem(*gin.Context)
+	UpdateItem(*gin.Context)
+	DeleteItem(*gin.Context)
+	ClearCart(*gin.Context)
+	Checkout(*gin.Context)
+}
+
+type cartHandler struct {
+	repo   repositories.CartRepository
+	orders repositories.OrderRepository
+	users  repositories.UserRepository
+}
+
+func NewCartHandler(repo repositories.CartRepository, orders repositories.OrderRepository, users repositories.UserRepository) CartHandler {
+	return &cartHandler{
+		repo:   repo,
+		orders: orders,
+		users:  users,
+	}
+}
+
+// respondCart answers the cart of userID priced at the current prices.
+func (h *cartHandler) respondCart(ctx *gin.Context, userID uint) {
+	cart, err := h.repo.GetCart(userID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to load cart"})
+		return
+	}
+	ctx.JSON(http.StatusOK, cart)
+}
+
+func (h *cartHandler) GetCart(ctx *gin.Context) {
+	principal, ok := currentPrincipal(ctx)
+	if !ok {
+		return
+	}
+	h.respondCart(ctx, principal.UserID)
+}
+
+// AddItem puts a product with its variant and modifiers into the cart. The
+// choices are checked against the product, its stock is not.
+func (h *cartHandler) AddItem(ctx *gin.Context) {
+	var input models.CartItemRequest
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if input.ProductID == 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "product_id is required"})
+		return
+	}
+	principal, ok := currentPrincipal(ctx)
+	if !ok {
+		return
+	}
+	if _, err := h.repo.AddCartItem(principal.UserID, input); errors.Is(err, gorm.ErrRecordNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		return
+	} else if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	h.respondCart(ctx, principal.UserID)
+}
+
+// UpdateItem replaces the quantity, variant and modifiers of a cart item,
+// its product stays.
+func (h *cartHandler) UpdateItem(ctx *gin.Context) {
+	itemID, ok := idParam(ctx, "item_id")
+	if !ok {
+		return
+	}
+	var input models.CartItemRequest
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	principal, ok := currentPrincipal(ctx)
+	if !ok {
+		return
+	}
+	if _, err := h.repo.UpdateCartItem(principal.UserID, itemID, input); errors.Is(err, gorm.ErrRecordNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Cart item not found"})
+		return
+	} else if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	h.respondCart(ctx, principal.UserID)
+}
+
+func (h *cartHandler) DeleteItem(ctx *gin.Context) {
+	itemID, ok := idParam(ctx, "item_id")
+	if !ok {
+		return
+	}
+	principal, ok := currentPrincipal(ctx)
+	if !ok {
+		return
+	}
+	if err := h.repo.DeleteCartItem(principal.UserID, itemID); errors.Is(err, gorm.ErrRecordNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Cart item not found"})
+		return
+	} else if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to delete cart item"})
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+func (h *cartHandler) ClearCart(ctx *gin.Context) {
+	principal, ok := currentPrincipal(ctx)
+	if !ok {
+		return
+	}
+	if err := h.repo.ClearCart(principal.UserID); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to clear cart"})
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+// Checkout reserves the stock of every item in the cart and places them as
+// one order, or fails leaving both the cart and the stock as they were.
+func (h *cartHandler) Checkout(ctx *gin.Context) {
+	principal, ok := currentPrincipal(ctx)
+	if !ok {
+		return
+	}
+	order, err := h.repo.Checkout(principal.UserID)
+	if errors.Is(err, repositories.ErrCartEmpty) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	} else if errors.Is(err, repositories.ErrItemUnavailable) || errors.Is(err, repositories.ErrOutOfStock) {
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	} else if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to place the order"})
+		return
+	}
+	details, ok := orderDetails(ctx, h.orders, h.users, order)
+	if !ok {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, details)
+}
@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"go_final/media"
+	"go_final/models"
+	"go_final/repositories"
+	"gorm.io/gorm"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// CatalogHandler manages the categories and tags of the menu.
+type CatalogHandler interface {
+	GetMenu(*gin.Context)
+	GetCategories(*gin.Context)
+	GetCategory(*gin.Context)
+	CreateCategory(*gin.Context)
+	UpdateCategory(*gin.Context)
+	DeleteCategory(*gin.Context)
+	GetTags(*gin.Context)
+	CreateTag(*gin.Context)
+	UpdateTag(*gin.Context)
+	DeleteTag(*gin.Context)
+}
+
+type catalogHandler struct {
+	repo  repositories.CatalogRepository
+	blobs media.BlobStore
+}
+
+func NewCatalogHandler(repo repositories.CatalogRepository, blobs media.BlobStore) CatalogHandler {
+	return &catalogHandler{
+		repo:  repo,
+		blobs: blobs,
+	}
+}
+
+// catalogError answers err, naming what was not found or taken.
+func catalogError(ctx *gin.Context, err error, what string) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "No such " + what})
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		ctx.JSON(http.StatusConflict, gin.H{"error": "A " + what + " with this name already exists"})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+func idParam(ctx *gin.Context, name string) (uint, bool) {
+	id, err := strconv.ParseUint(ctx.Param(name), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func (h *catalogHandler) GetMenu(ctx *gin.Context) {
+	menu, err := h.repo.GetMenu()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	for _, section := range menu {
+		for i := range section.Products {
+			resolveImage(h.blobs, &section.Products[i])
+		}
+	}
+	ctx.JSON(http.StatusOK, menu)
+}
+
+func (h *catalogHandler) GetCategories(ctx *gin.Context) {
+	categories, err := h.repo.GetCategories()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, categories)
+}
+
+func (h *catalogHandler) GetCategory(ctx *gin.Context) {
+	id, ok := idParam(ctx, "category_id")
+	if !ok {
+		return
+	}
+	category, err := h.repo.GetCategory(id)
+	if err != nil {
+		catalogError(ctx, err, "category")
+		return
+	}
+	ctx.JSON(http.StatusOK, category)
+}
+
+func (h *catalogHandler) CreateCategory(ctx *gin.Context) {
+	var category models.Category
+	if err := ctx.ShouldBindJSON(&category); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	category.Name = strings.TrimSpace(category.Name)
+
+	category, err := h.repo.CreateCategory(category)
+	if err != nil {
+		catalogError(ctx, err, "category")
+		return
+	}
+	ctx.JSON(http.StatusOK, category)
+}
+
+// UpdateCategory replaces the name, description and position of a category.
+func (h *catalogHandler) UpdateCategory(ctx *gin.Context) {
+	id, ok := idParam(ctx, "category_id")
+	if !ok {
+		return
+	}
+	var category models.Category
+	if err := ctx.ShouldBindJSON(&category); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	category.ID = id
+	category.Name = strings.TrimSpace(category.Name)
+
+	category, err := h.repo.UpdateCategory(category)
+	if err != nil {
+		catalogError(ctx, err, "category")
+		return
+	}
+	ctx.JSON(http.StatusOK, category)
+}
+
+// DeleteCategory removes a category from the menu, its products stay.
+func (h *catalogHandler) DeleteCategory(ctx *gin.Context) {
+	id, ok := idParam(ctx, "category_id")
+	if !ok {
+		return
+	}
+	if err := h.repo.DeleteCategory(id); err != nil {
+		catalogError(ctx, err, "category")
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+func (h *catalogHandler) GetTags(ctx *gin.Context) {
+	tags, err := h.repo.GetTags()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, tags)
+}
+
+func (h *catalogHandler) CreateTag(ctx *gin.Context) {
+	var tag models.Tag
+	if err := ctx.ShouldBindJSON(&tag); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	tag.Name = strings.ToLower(strings.TrimSpace(tag.Name))
+
+	tag, err := h.repo.CreateTag(tag)
+	if err != nil {
+		catalogError(ctx, err, "tag")
+		return
+	}
+	ctx.JSON(http.StatusOK, tag)
+}
+
+func (h *catalogHandler) UpdateTag(ctx *gin.Context) {
+	id, ok := idParam(ctx, "tag_id")
+	if !ok {
+		return
+	}
+	var tag models.Tag
+	if err := ctx.ShouldBindJSON(&tag); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	tag.ID = id
+	tag.Name = strings.ToLower(strings.TrimSpace(tag.Name))
+
+	tag, err := h.repo.UpdateTag(tag)
+	if err != nil {
+		catalogError(ctx, err, "tag")
+		return
+	}
+	ctx.JSON(http.StatusOK, tag)
+}
+
+func (h *catalogHandler) DeleteTag(ctx *gin.Context) {
+	id, ok := idParam(ctx, "tag_id")
+	if !ok {
+		return
+	}
+	if err := h.repo.DeleteTag(id); err != nil {
+		catalogError(ctx, err, "tag")
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"go_final/migrations"
+	"go_final/repositories"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const readinessTimeout = 2 * time.Second
+
+// HealthState is shared between the HTTP server lifecycle and the probe
+// endpoints. Once draining is set the instance reports itself as not ready
+// so load balancers stop routing new requests to it.
+type HealthState struct {
+	draining atomic.Bool
+}
+
+func (s *HealthState) SetDraining() {
+	s.draining.Store(true)
+}
+
+func (s *HealthState) Draining() bool {
+	return s.draining.Load()
+}
+
+type HealthHandler interface {
+	Live(*gin.Context)
+	Ready(*gin.Context)
+}
+
+type healthHandler struct {
+	state *HealthState
+	store repositories.Store
+	// migrator knows the migrations of SQL stores, migratorErr is why it
+	// could not be built
+	migrator    *migrations.Migrator
+	migratorErr error
+}
+
+func NewHealthHandler(state *HealthState, store repositories.Store) HealthHandler {
+	h := &healthHandler{
+		state: state,
+		store: store,
+	}
+	if sqlStore, ok := store.(repositories.SQLStore); ok {
+		h.migrator, h.migratorErr = migrations.New(sqlStore.DB(), nil)
+	}
+	return h
+}
+
+type healthCheck struct {
+	Status  string   `json:"status"`
+	Error   string   `json:"error,omitempty"`
+	Latency string   `json:"latency,omitempty"`
+	Pending []string `json:"pending,omitempty"`
+}
+
+type poolStats struct {
+	MaxOpen      int    `json:"max_open"`
+	Open         int    `json:"open"`
+	InUse        int    `json:"in_use"`
+	Idle         int    `json:"idle"`
+	WaitCount    int64  `json:"wait_count"`
+	WaitDuration string `json:"wait_duration"`
+}
+
+// Live only reports that the process is able to serve requests, it never
+// looks at dependencies so a database outage does not restart the app.
+func (h *healthHandler) Live(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// Ready reports whether this instance should receive traffic: it is not
+// draining, the database answers and every migration has been applied.
+func (h *healthHandler) Ready(ctx *gin.Context) {
+	ready := true
+	checks := gin.H{}
+	response := gin.H{"checks": checks}
+
+	if h.state.Draining() {
+		ready = false
+		response["status"] = "draining"
+	}
+
+	if sqlStore, ok := h.store.(repositories.SQLStore); ok {
+		database, pool := h.checkDatabase(ctx.Request.Context(), sqlStore)
+		checks["database"] = database
+		if pool != nil {
+			response["pool"] = pool
+		}
+
+		migrationCheck := h.checkMigrations(ctx.Request.Context())
+		checks["migrations"] = migrationCheck
+
+		ready = ready && database.Status == "ok" && migrationCheck.Status == "ok"
+	} else {
+		checks["database"] = healthCheck{Status: "ok"}
+	}
+
+	if _, ok := response["status"]; !ok {
+		response["status"] = "ok"
+		if !ready {
+			response["status"] = "unavailable"
+		}
+	}
+
+	if !ready {
+		ctx.JSON(http.StatusServiceUnavailable, response)
+		return
+	}
+	ctx.JSON(http.StatusOK, response)
+}
+
+func (h *healthHandler) checkDatabase(ctx context.Context, store repositories.SQLStore) (healthCheck, *poolStats) {
+	sqlDB, err := store.DB().DB()
+	if err != nil {
+		return healthCheck{Status: "error", Error: err.Error()}, nil
+	}
+
+	stats := sqlDB.Stats()
+	pool := &poolStats{
+		MaxOpen:      stats.MaxOpenConnections,
+		Open:         stats.OpenConnections,
+		InUse:        stats.InUse,
+		Idle:         stats.Idle,
+		WaitCount:    stats.WaitCount,
+		WaitDuration: stats.WaitDuration.String(),
+	}
+
+	pingCtx, cancel := context.WithTimeout(ctx, readinessTimeout)
+	defer cancel()
+
+	start := time.Now()
+	if err := sqlDB.PingContext(pingCtx); err != nil {
+		return healthCheck{Status: "error", Error: err.Error()}, pool
+	}
+	return healthCheck{Status: "ok", Latency: time.Since(start).String()}, pool
+}
+
+func (h *healthHandler) checkMigrations(ctx context.Context) healthCheck {
+	if h.migratorErr != nil {
+		return healthCheck{Status: "error", Error: h.migratorErr.Error()}
+	}
+
+	queryCtx, cancel := context.WithTimeout(ctx, readinessTimeout)
+	defer cancel()
+
+	pending, err := h.migrator.WithContext(queryCtx).Pending()
+	if err != nil {
+		return healthCheck{Status: "error", Error: err.Error()}
+	}
+	if len(pending) == 0 {
+		return healthCheck{Status: "ok"}
+	}
+
+	check := healthCheck{Status: "pending"}
+	for _, m := range pending {
+		check.Pending = append(check.Pending, fmt.Sprintf("%04d_%s", m.Version, m.Name))
+	}
+	return check
+}
@@ -0,0 +1,17 @@
+package handlers
+
+import (
+	"go_final/auth"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JWKS publishes the public signing keys so other services can verify
+// tokens issued by this API without sharing a secret.
+func JWKS(keys *auth.KeySet) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Header("Cache-Control", "public, max-age=300")
+		ctx.JSON(http.StatusOK, keys.JWKS())
+	}
+}
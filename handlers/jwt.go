@@ -1,31 +1,66 @@
 package handlers
 
 import (
-	"fmt"
-	"github.com/golang-jwt/jwt/v5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"github.com/gin-gonic/gin"
+	"go_final/auth"
+	"go_final/config"
 	"go_final/models"
-	"os"
+	"net/http"
 	"time"
 )
 
-func GenerateToken(userID uint, role models.UserRole) string {
-	claims := jwt.MapClaims{
-		"exp":    time.Now().Add(time.Hour * 24).Unix(),
-		"iat":    time.Now().Unix(),
-		"userID": userID,
-		"role":   role,
+func GenerateToken(keys *auth.KeySet, cfg config.JWTConfig, userID uint, role models.UserRole, amr []string) (string, error) {
+	jti, err := randomToken(16)
+	if err != nil {
+		return "", err
 	}
+	return keys.Sign(auth.NewClaims(cfg, userID, role, jti, amr))
+}
+
+func ValidateToken(keys *auth.KeySet, cfg config.JWTConfig, token string) (*auth.Claims, error) {
+	return auth.ParseClaims(keys, cfg, token)
+}
+
+// GenerateMFAToken signs the short-lived token that carries a sign-in from
+// the password step to the second factor.
+func GenerateMFAToken(keys *auth.KeySet, cfg config.JWTConfig, userID uint, role models.UserRole, ttl time.Duration) (string, error) {
+	jti, err := randomToken(16)
+	if err != nil {
+		return "", err
+	}
+	return keys.Sign(auth.NewMFAClaims(cfg, userID, role, jti, ttl))
+}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	t, _ := token.SignedString([]byte(os.Getenv("JWT_SECRET")))
-	return t
+func ValidateMFAToken(keys *auth.KeySet, cfg config.JWTConfig, token string) (*auth.Claims, error) {
+	return auth.ParseMFAClaims(keys, cfg, token)
 }
 
-func ValidateToken(token string) (*jwt.Token, error) {
-	return jwt.Parse(token, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(os.Getenv("JWT_SECRET")), nil
-	})
+// randomToken returns n random bytes encoded for use in URLs and headers.
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken is used to store refresh tokens, they are random enough that a
+// plain SHA-256 is sufficient.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// currentPrincipal returns the authenticated caller or answers 401 when the
+// route was not protected by AuthorizeJWT.
+func currentPrincipal(ctx *gin.Context) (auth.Principal, bool) {
+	principal, ok := auth.CurrentPrincipal(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+	}
+	return principal, ok
 }
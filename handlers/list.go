@@ -0,0 +1,19 @@
+package handlers
+
+import (
+	"go_final/repositories"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// listQuery parses the pagination, sort and filter parameters of a list
+// request, answering 400 when they do not fit spec.
+func listQuery[T any](ctx *gin.Context, spec repositories.ListSpec[T]) (repositories.ListQuery, bool) {
+	query, err := repositories.ParseListQuery(ctx.Request.URL.Query(), spec)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return query, false
+	}
+	return query, true
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"go_final/config"
+	"go_final/models"
+	"go_final/repositories"
+	"strings"
+	"time"
+)
+
+// loginGuard tracks failed sign-ins per email address and per client IP and
+// locks either out with exponential backoff. Addresses are tracked whether
+// or not they are registered, so lockouts reveal nothing about accounts.
+type loginGuard struct {
+	repo repositories.LoginThrottleRepository
+	cfg  config.AccountConfig
+}
+
+func emailSubject(email string) string {
+	return "email:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipSubject(ip string) string {
+	return "ip:" + ip
+}
+
+// lockedFor returns how long the sign-in from ip for email is still locked
+// out, zero if it is not.
+func (g *loginGuard) lockedFor(email, ip string, now time.Time) (time.Duration, error) {
+	var wait time.Duration
+	for _, subject := range []string{emailSubject(email), ipSubject(ip)} {
+		throttle, err := g.repo.GetLoginThrottle(subject)
+		if err != nil {
+			return 0, err
+		}
+		if throttle.LockedUntil != nil && throttle.LockedUntil.Sub(now) > wait {
+			wait = throttle.LockedUntil.Sub(now)
+		}
+	}
+	return wait, nil
+}
+
+// fail records a failed sign-in and locks out every subject that reached
+// its limit.
+func (g *loginGuard) fail(email, ip string, now time.Time) error {
+	limits := map[string]int{
+		emailSubject(email): g.cfg.LoginMaxFailures,
+		ipSubject(ip):       g.cfg.LoginIPMaxFailures,
+	}
+	for subject, limit := range limits {
+		throttle, err := g.record(subject, now)
+		if err != nil {
+			return err
+		}
+		if throttle.Failures >= limit {
+			if err := g.repo.LockLogin(subject, now.Add(g.lockout(throttle.Failures-limit))); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// record counts a failure, starting over when the previous one is older
+// than the longest lockout.
+func (g *loginGuard) record(subject string, now time.Time) (models.LoginThrottle, error) {
+	throttle, err := g.repo.GetLoginThrottle(subject)
+	if err != nil {
+		return throttle, err
+	}
+	if throttle.Failures > 0 && now.Sub(throttle.LastFailureAt) > g.cfg.LoginMaxLockout.Duration {
+		if err := g.repo.ResetLoginThrottle(subject); err != nil {
+			return throttle, err
+		}
+	}
+	return g.repo.RecordLoginFailure(subject, now)
+}
+
+// lockout doubles the base lockout for every failure past the limit.
+func (g *loginGuard) lockout(excess int) time.Duration {
+	lockout := g.cfg.LoginLockout.Duration
+	for i := 0; i < excess && lockout < g.cfg.LoginMaxLockout.Duration; i++ {
+		lockout *= 2
+	}
+	if lockout > g.cfg.LoginMaxLockout.Duration {
+		lockout = g.cfg.LoginMaxLockout.Duration
+	}
+	return lockout
+}
+
+// succeed clears the failures of the email address. The IP keeps its count,
+// otherwise signing in to one's own account would reset it.
+func (g *loginGuard) succeed(email string) error {
+	return g.repo.ResetLoginThrottle(emailSubject(email))
+}
+
+func (g *loginGuard) unlock(email string) error {
+	return g.repo.ResetLoginThrottle(emailSubject(email))
+}
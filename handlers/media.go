@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"errors"
+	"go_final/media"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Media serves the files of the blob store. Keys never change content, a
+// new upload gets a new key, so clients may cache them for good.
+func Media(blobs media.BlobStore) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		key := strings.TrimPrefix(ctx.Param("key"), "/")
+		file, err := blobs.Open(key)
+		if errors.Is(err, media.ErrNotFound) || errors.Is(err, media.ErrInvalidKey) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "No such file"})
+			return
+		}
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer file.Close()
+
+		ctx.Header("Cache-Control", "public, max-age=31536000, immutable")
+		ctx.Header("X-Content-Type-Options", "nosniff")
+		ctx.Header("Content-Type", mime.TypeByExtension(path.Ext(key)))
+		ctx.Status(http.StatusOK)
+		_, _ = io.Copy(ctx.Writer, file)
+	}
+}
@@ -0,0 +1,343 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"go_final/auth"
+	"go_final/models"
+	"gorm.io/gorm"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const recoveryCodeCount = 10
+
+var recoveryEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// newRecoveryCodes returns the codes shown to the user once and the hashes
+// that are stored in their place.
+func newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		raw := make([]byte, 10)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, nil, err
+		}
+		code := strings.ToLower(recoveryEncoding.EncodeToString(raw))
+		codes[i] = code[0:4] + "-" + code[4:8] + "-" + code[8:12] + "-" + code[12:16]
+		hashes[i] = hashRecoveryCode(codes[i])
+	}
+	return codes, hashes, nil
+}
+
+// hashRecoveryCode ignores case, dashes and spaces so that codes can be
+// typed the way they are read.
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	code = strings.NewReplacer("-", "", " ", "").Replace(code)
+	return hashToken(code)
+}
+
+func (h *userHandler) mfaRequired(role models.UserRole) bool {
+	for _, required := range h.account.MFARequiredRoles {
+		if required == string(role) {
+			return true
+		}
+	}
+	return false
+}
+
+// verifySecondFactor checks the TOTP code, or when allowRecovery is set the
+// recovery code, in input. Failures count towards the sign-in lockout of the
+// user's email address, so codes cannot be guessed through any endpoint. It
+// writes the error response itself, with failStatus for a wrong code.
+func (h *userHandler) verifySecondFactor(ctx *gin.Context, userID uint, email string, input models.MFAVerifyRequest, allowRecovery bool, failStatus int) bool {
+	if input.Code == "" && (input.RecoveryCode == "" || !allowRecovery) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "An authentication code is required"})
+		return false
+	}
+
+	now := time.Now()
+	ip := ctx.ClientIP()
+	wait, err := h.guard.lockedFor(email, ip, now)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+	if wait > 0 {
+		ctx.Header("Retry-After", strconv.Itoa(int(wait.Seconds()+1)))
+		ctx.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many failed sign-in attempts, try again later"})
+		return false
+	}
+
+	mfa, err := h.mfa.GetMFA(userID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+	if mfa.EnabledAt == nil {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is not enabled"})
+		return false
+	}
+
+	ok := false
+	if input.Code != "" {
+		// a code is accepted once, even within its validity window
+		if step, valid := auth.VerifyTOTP(mfa.TOTPSecret, input.Code, now); valid {
+			ok, err = h.mfa.UseTOTPStep(userID, step)
+		}
+	} else {
+		ok, err = h.mfa.UseRecoveryCode(userID, hashRecoveryCode(input.RecoveryCode))
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+
+	if !ok {
+		if err := h.guard.fail(email, ip, now); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return false
+		}
+		ctx.JSON(failStatus, gin.H{"error": "Invalid authentication code"})
+		return false
+	}
+	if err := h.guard.succeed(email); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+	return true
+}
+
+// SignInMFA is the second step of a sign-in with two-factor authentication.
+// The MFA token from the first step can be used once.
+func (h *userHandler) SignInMFA(ctx *gin.Context) {
+	var input models.MFASignInRequest
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	claims, err := ValidateMFAToken(h.keys, h.jwt, input.MFAToken)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired MFA token"})
+		return
+	}
+	userID, _ := claims.UserID()
+	revoked, err := h.tokens.IsAccessTokenRevoked(claims.ID, userID, claims.IssuedAtTime())
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	user, err := h.repo.GetUser(int(userID))
+	if revoked || err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired MFA token"})
+		return
+	}
+
+	if !h.verifySecondFactor(ctx, user.ID, user.Email, input.MFAVerifyRequest, true, http.StatusUnauthorized) {
+		return
+	}
+	if err := h.tokens.RevokeAccessToken(claims.ID, claims.ExpiresAt.Time); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.signIn(ctx, user.ID, user.Role, true)
+}
+
+// EnrollTOTP creates a new secret for the caller. It only takes effect once
+// ConfirmTOTP receives a code generated from it.
+func (h *userHandler) EnrollTOTP(ctx *gin.Context) {
+	principal, ok := currentPrincipal(ctx)
+	if !ok {
+		return
+	}
+
+	mfa, err := h.mfa.GetMFA(principal.UserID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if mfa.EnabledAt != nil {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is already enabled"})
+		return
+	}
+
+	user, err := h.repo.GetUser(int(principal.UserID))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "No such user in database!"})
+		return
+	}
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.mfa.SaveTOTPSecret(user.ID, secret); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"secret":           secret,
+		"provisioning_uri": auth.TOTPProvisioningURI(h.account.MFAIssuer, user.Email, secret),
+	})
+}
+
+// ConfirmTOTP enables two-factor authentication and answers with the
+// recovery codes and a token pair that satisfies roles requiring it.
+func (h *userHandler) ConfirmTOTP(ctx *gin.Context) {
+	var input models.MFACodeRequest
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	principal, ok := currentPrincipal(ctx)
+	if !ok {
+		return
+	}
+
+	mfa, err := h.mfa.GetMFA(principal.UserID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if mfa.EnabledAt != nil {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is already enabled"})
+		return
+	}
+	if mfa.TOTPSecret == "" {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication enrollment has not been started"})
+		return
+	}
+	step, valid := auth.VerifyTOTP(mfa.TOTPSecret, input.Code, time.Now())
+	if !valid {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid authentication code"})
+		return
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	err = h.mfa.EnableTOTP(principal.UserID, step, hashes)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is already enabled"})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	familyID, err := randomToken(16)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	tokens, err := h.issueTokens(principal.UserID, principal.Role, familyID, 0, true)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	tokens["recovery_codes"] = codes
+	tokens["message"] = "Two-factor authentication enabled, store the recovery codes in a safe place"
+	ctx.JSON(http.StatusOK, tokens)
+}
+
+func (h *userHandler) DisableTOTP(ctx *gin.Context) {
+	var input models.MFAVerifyRequest
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	principal, ok := currentPrincipal(ctx)
+	if !ok {
+		return
+	}
+	if h.mfaRequired(principal.Role) {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "Two-factor authentication is required for your role"})
+		return
+	}
+
+	user, err := h.repo.GetUser(int(principal.UserID))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "No such user in database!"})
+		return
+	}
+	if !h.verifySecondFactor(ctx, user.ID, user.Email, input, true, http.StatusBadRequest) {
+		return
+	}
+	if err := h.mfa.DisableTOTP(user.ID); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+// RegenerateRecoveryCodes replaces every recovery code of the caller. It
+// takes a TOTP code, not a recovery code.
+func (h *userHandler) RegenerateRecoveryCodes(ctx *gin.Context) {
+	var input models.MFACodeRequest
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	principal, ok := currentPrincipal(ctx)
+	if !ok {
+		return
+	}
+
+	user, err := h.repo.GetUser(int(principal.UserID))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "No such user in database!"})
+		return
+	}
+	if !h.verifySecondFactor(ctx, user.ID, user.Email, models.MFAVerifyRequest{Code: input.Code}, false, http.StatusBadRequest) {
+		return
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.mfa.ReplaceRecoveryCodes(user.ID, hashes); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
+
+// ResetUserMFA turns off two-factor authentication for a user who lost both
+// the authenticator and the recovery codes, and signs them out everywhere.
+func (h *userHandler) ResetUserMFA(ctx *gin.Context) {
+	intID, err := strconv.Atoi(ctx.Param("user_id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.repo.GetUser(intID)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "No such user in database!"})
+		return
+	}
+
+	if err := h.mfa.DisableTOTP(user.ID); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.tokens.RevokeUserTokens(user.ID); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
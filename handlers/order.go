@@ -1,160 +1,232 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"github.com/gin-gonic/gin"
+	"go_final/auth"
 	"go_final/models"
+	"go_final/receipt"
 	"go_final/repositories"
 	"net/http"
 	"strconv"
 )
 
+// OrderHandler serves orders, which are placed by checking out a cart and
+// are not edited afterwards.
 type OrderHandler interface {
-	OrderProducts(*gin.Context)
-	UpdateOrder(*gin.Context)
 	UpdateOrderStatus(ctx *gin.Context)
-	DeleteOrder(*gin.Context)
-	DeleteOrderItem(*gin.Context)
 	GetOrderItems(*gin.Context)
 	GetOrders(*gin.Context)
 	GetOrderByID(*gin.Context)
+	GetReceipt(*gin.Context)
+	SetDiscount(*gin.Context)
 }
 
 type orderHandler struct {
-	repo repositories.OrderRepository
+	repo     repositories.OrderRepository
+	users    repositories.UserRepository
+	receipts receipt.Renderer
 }
 
-func NewOrderHandler() OrderHandler {
+func NewOrderHandler(repo repositories.OrderRepository, users repositories.UserRepository, receipts receipt.Renderer) OrderHandler {
 	return &orderHandler{
-		repo: repositories.NewOrderRepository(),
+		repo:     repo,
+		users:    users,
+		receipts: receipts,
 	}
 }
 
-func (h *orderHandler) GetOrderByID(ctx *gin.Context) {
-	orderIDStr := ctx.Param("order_id")
-	orderID, err := strconv.Atoi(orderIDStr)
+// authorizedOrder loads the order named by the :order_id path parameter and
+// checks that the caller may perform action on it. It writes the error
+// response itself when it fails.
+func (h *orderHandler) authorizedOrder(ctx *gin.Context, action auth.Action) (models.Order, bool) {
+	principal, ok := currentPrincipal(ctx)
+	if !ok {
+		return models.Order{}, false
+	}
+	orderID, err := strconv.Atoi(ctx.Param("order_id"))
 	if err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
-		return
+		return models.Order{}, false
 	}
 	order, err := h.repo.GetOrderByID(uint(orderID))
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err})
-		return
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+		return models.Order{}, false
 	}
-
-	ctx.JSON(http.StatusOK, order)
+	if !auth.Can(principal, action, auth.Order(order)) {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "Not allowed to " + string(action) + " this order"})
+		return models.Order{}, false
+	}
+	return order, true
 }
 
-func (h *orderHandler) GetOrders(ctx *gin.Context) {
-	userID := ctx.GetFloat64("userID")
-	orders, err := h.repo.GetOrders(uint(userID))
+// orderDetails puts together the full view of order. It writes the error
+// response itself when it fails.
+func orderDetails(ctx *gin.Context, orders repositories.OrderRepository, users repositories.UserRepository, order models.Order) (models.OrderDetails, bool) {
+	items, err := orders.GetOrderItems(order.ID)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to load order items"})
+		return models.OrderDetails{}, false
 	}
-
-	ctx.JSON(http.StatusOK, orders)
+	// orders of deleted accounts have no user
+	var user *models.APIUser
+	if order.UserID != 0 {
+		if found, err := users.GetUser(int(order.UserID)); err == nil {
+			user = &found
+		}
+	}
+	details, err := models.NewOrderDetails(order, items, user)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return models.OrderDetails{}, false
+	}
+	return details, true
 }
 
-func (h *orderHandler) GetOrderItems(ctx *gin.Context) {
-	orderIDStr := ctx.Param("order_id")
-	orderID, err := strconv.Atoi(orderIDStr)
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+// GetOrderByID answers the order with its items, totals and customer.
+func (h *orderHandler) GetOrderByID(ctx *gin.Context) {
+	order, ok := h.authorizedOrder(ctx, auth.ActionRead)
+	if !ok {
 		return
 	}
-	order, err := h.repo.GetOrderItems(uint(orderID))
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err})
+	details, ok := orderDetails(ctx, h.repo, h.users, order)
+	if !ok {
 		return
 	}
 
-	ctx.JSON(http.StatusOK, order)
+	ctx.JSON(http.StatusOK, details)
 }
 
-func (h *orderHandler) OrderProducts(ctx *gin.Context) {
-	var input models.CartRequest
-	if err := ctx.ShouldBindJSON(&input); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+// GetReceipt prints the order as plain text or PDF, chosen by the format
+// query parameter or else the Accept header.
+func (h *orderHandler) GetReceipt(ctx *gin.Context) {
+	format := ctx.Query("format")
+	switch format {
+	case "text", "pdf":
+	case "":
+		format = "text"
+		if ctx.NegotiateFormat("text/plain", "application/pdf") == "application/pdf" {
+			format = "pdf"
+		}
+	default:
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Receipt format must be text or pdf"})
 		return
 	}
-	userID := ctx.GetFloat64("userID")
-	if err := h.repo.OrderProducts(uint(userID), input.CartItems); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+	order, ok := h.authorizedOrder(ctx, auth.ActionRead)
+	if !ok {
+		return
+	}
+	details, ok := orderDetails(ctx, h.repo, h.users, order)
+	if !ok {
 		return
 	}
 
-	ctx.JSON(http.StatusOK, input)
+	if format == "text" {
+		ctx.Data(http.StatusOK, "text/plain; charset=utf-8", h.receipts.Text(details))
+		return
+	}
+	pdf, err := h.receipts.PDF(details)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to render receipt"})
+		return
+	}
+	ctx.Header("Content-Disposition", fmt.Sprintf(`inline; filename="receipt-%d.pdf"`, order.ID))
+	ctx.Data(http.StatusOK, "application/pdf", pdf)
 }
 
-func (h *orderHandler) UpdateOrder(ctx *gin.Context) {
-	var input models.CartRequest
+// SetDiscount grants a discount of a whole percentage on an order that is
+// still open, 0 takes it back.
+func (h *orderHandler) SetDiscount(ctx *gin.Context) {
+	var input models.DiscountRequest
 	if err := ctx.ShouldBindJSON(&input); err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	userID := ctx.GetFloat64("userID")
-	if err := h.repo.UpdateOrder(uint(userID), input.CartItems); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	order, ok := h.authorizedOrder(ctx, auth.ActionDiscount)
+	if !ok {
 		return
 	}
 
-	ctx.JSON(http.StatusOK, input)
-}
-
-func (h *orderHandler) DeleteOrder(ctx *gin.Context) {
-	userID := ctx.GetFloat64("userID")
-	if err := h.repo.DeleteOrder(uint(userID)); err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to delete order"})
+	order, err := h.repo.SetOrderDiscount(order.ID, *input.Percent)
+	if errors.Is(err, repositories.ErrOrderClosed) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Cannot discount a confirmed or canceled order"})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to set discount"})
+		return
+	}
+	details, ok := orderDetails(ctx, h.repo, h.users, order)
+	if !ok {
 		return
 	}
-	ctx.Status(http.StatusNoContent)
-}
 
-func (h *orderHandler) DeleteOrderItem(ctx *gin.Context) {
-	userID := ctx.GetFloat64("userID")
+	ctx.JSON(http.StatusOK, details)
+}
 
-	orderItemIDStr := ctx.Param("order_item_id")
-	orderItemID, err := strconv.Atoi(orderItemIDStr)
+// GetOrders lists every order to callers that may read any, and the caller's
+// own orders to everyone else. Integrations calling with an API key have
+// none, so they need the permission.
+func (h *orderHandler) GetOrders(ctx *gin.Context) {
+	principal, ok := currentPrincipal(ctx)
+	if !ok {
+		return
+	}
+	query, ok := listQuery(ctx, repositories.OrderListSpec)
+	if !ok {
+		return
+	}
+	if !principal.Has(auth.OrdersReadAny) {
+		if principal.APIKeyID != 0 {
+			ctx.JSON(http.StatusForbidden, gin.H{"error": "Missing permission: " + string(auth.OrdersReadAny)})
+			return
+		}
+		query = query.Where("user_id", int64(principal.UserID))
+	}
+	orders, err := h.repo.ListOrders(query)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order item ID"})
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	if err = h.repo.DeleteOrderItem(uint(userID), uint(orderItemID)); err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to delete order item"})
+	ctx.JSON(http.StatusOK, orders)
+}
+
+func (h *orderHandler) GetOrderItems(ctx *gin.Context) {
+	order, ok := h.authorizedOrder(ctx, auth.ActionRead)
+	if !ok {
+		return
+	}
+	orderItems, err := h.repo.GetOrderItems(order.ID)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	ctx.Status(http.StatusNoContent)
+	ctx.JSON(http.StatusOK, orderItems)
 }
 
 func (h *orderHandler) UpdateOrderStatus(ctx *gin.Context) {
-	userID := ctx.GetFloat64("userID")
-	userStatus := ctx.GetString("role")
-	orderIDStr := ctx.Param("order_id")
-	orderID, err := strconv.Atoi(orderIDStr)
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+	newStatus := models.OrderStatus(ctx.Param("status"))
+	order, ok := h.authorizedOrder(ctx, auth.AdvanceTo(newStatus))
+	if !ok {
 		return
 	}
-	newStatus := ctx.Param("status")
 
-	order, err := h.repo.GetOrderByID(uint(orderID))
-	if err != nil {
-		ctx.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+	if !isStatusTransitionValid(order.OrderStatus, newStatus) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Status transition not allowed"})
 		return
 	}
 
-	if order.UserID != uint(userID) && !isStatusTransitionAllowed(order.OrderStatus, models.OrderStatus(newStatus), userStatus) {
-		ctx.JSON(
-			http.StatusUnauthorized,
-			gin.H{"error": "Status transition not allowed" + strconv.FormatBool(order.UserID == uint(userID)) + strconv.FormatBool(isStatusTransitionAllowed(order.OrderStatus, models.OrderStatus(newStatus), userStatus))},
-		)
+	err := h.repo.UpdateOrderStatus(order.ID, order.OrderStatus, newStatus)
+	if errors.Is(err, repositories.ErrOrderStatusChanged) {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "Order status has changed, reload the order"})
 		return
 	}
-
-	if err = h.repo.UpdateOrderStatus(uint(orderID), newStatus); err != nil {
+	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update order status"})
 		return
 	}
@@ -162,33 +234,21 @@ func (h *orderHandler) UpdateOrderStatus(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, gin.H{"message": "Order status updated successfully"})
 }
 
-func isStatusTransitionAllowed(currentStatus, newStatus models.OrderStatus, userRole string) bool {
-	switch userRole {
-	case "admin":
-		return isAdminTransitionValid(currentStatus, newStatus)
-	case "customer":
-		return isCustomerTransitionValid(currentStatus, newStatus)
-	}
-	return false
-}
-
-func isAdminTransitionValid(current, new models.OrderStatus) bool {
+// isStatusTransitionValid follows the order life cycle: the canteen staff
+// moves it along pending -> accepted -> ready -> out -> delivered, the
+// customer confirms a delivered order and an order can be canceled until it
+// is confirmed.
+func isStatusTransitionValid(current, new models.OrderStatus) bool {
 	validTransitions := map[models.OrderStatus]models.OrderStatus{
-		models.PENDING:  models.ACCEPTED,
-		models.ACCEPTED: models.READY,
-		models.READY:    models.OUT,
-		models.OUT:      models.DELIVERED,
+		models.PENDING:   models.ACCEPTED,
+		models.ACCEPTED:  models.READY,
+		models.READY:     models.OUT,
+		models.OUT:       models.DELIVERED,
+		models.DELIVERED: models.CONFIRMED,
 	}
-	nextStatus, ok := validTransitions[current]
-	return ok && new == nextStatus
-}
-
-func isCustomerTransitionValid(current, new models.OrderStatus) bool {
 	if new == models.CANCELED {
-		return true
-	}
-	if current == models.DELIVERED && new == models.CONFIRMED {
-		return true
+		return current != models.CANCELED && current != models.CONFIRMED
 	}
-	return false
+	nextStatus, ok := validTransitions[current]
+	return ok && new == nextStatus
 }
@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"go_final/models"
+	"go_final/repositories"
+	"gorm.io/gorm"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// PrivacyHandler answers data protection requests: exporting the data of a
+// user and erasing it.
+type PrivacyHandler interface {
+	ExportMe(*gin.Context)
+	AnonymizeUser(*gin.Context)
+}
+
+type privacyHandler struct {
+	users     repositories.UserRepository
+	orders    repositories.OrderRepository
+	tokens    repositories.TokenRepository
+	throttles repositories.LoginThrottleRepository
+}
+
+func NewPrivacyHandler(users repositories.UserRepository, orders repositories.OrderRepository, tokens repositories.TokenRepository, throttles repositories.LoginThrottleRepository) PrivacyHandler {
+	return &privacyHandler{
+		users:     users,
+		orders:    orders,
+		tokens:    tokens,
+		throttles: throttles,
+	}
+}
+
+// ExportMe hands out the profile and order history of the caller as a JSON
+// file, or with ?format=zip as an archive of profile.json and orders.json.
+func (h *privacyHandler) ExportMe(ctx *gin.Context) {
+	format := ctx.DefaultQuery("format", "json")
+	if format != "json" && format != "zip" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "format must be json or zip"})
+		return
+	}
+	principal, ok := currentPrincipal(ctx)
+	if !ok {
+		return
+	}
+
+	export, err := h.export(principal.UserID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "No such user in database!"})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	name := fmt.Sprintf("sdu-canteen-export-%d-%s", principal.UserID, export.ExportedAt.Format("20060102"))
+	ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, name, format))
+	if format == "json" {
+		ctx.IndentedJSON(http.StatusOK, export)
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+	ctx.Header("Content-Type", "application/zip")
+	archive := zip.NewWriter(ctx.Writer)
+	files := []struct {
+		name    string
+		content interface{}
+	}{
+		{"profile.json", export.User},
+		{"orders.json", export.Orders},
+	}
+	for _, file := range files {
+		w, err := archive.CreateHeader(&zip.FileHeader{Name: file.name, Method: zip.Deflate, Modified: export.ExportedAt})
+		if err != nil {
+			ctx.Error(err)
+			return
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(file.content); err != nil {
+			ctx.Error(err)
+			return
+		}
+	}
+	if err := archive.Close(); err != nil {
+		ctx.Error(err)
+	}
+}
+
+func (h *privacyHandler) export(userID uint) (models.UserExport, error) {
+	user, err := h.users.GetUser(int(userID))
+	if err != nil {
+		return models.UserExport{}, err
+	}
+	orders, err := h.orders.GetOrders(userID)
+	if err != nil {
+		return models.UserExport{}, err
+	}
+
+	export := models.UserExport{ExportedAt: time.Now().UTC(), User: user, Orders: []models.OrderExport{}}
+	for _, order := range orders {
+		items, err := h.orders.GetOrderItems(order.ID)
+		if err != nil {
+			return models.UserExport{}, err
+		}
+		exported := models.OrderExport{
+			ID:        order.ID,
+			Status:    order.OrderStatus,
+			CreatedAt: order.CreatedAt,
+			UpdatedAt: order.UpdatedAt,
+			Total:     order.Total,
+			Items:     make([]models.OrderItemExport, 0, len(items)),
+		}
+		for _, item := range items {
+			exportedItem := models.OrderItemExport{
+				ProductID:   item.ProductID,
+				ProductName: item.ProductName,
+				Variant:     item.VariantName,
+				Quantity:    item.Quantity,
+				Price:       item.Price,
+			}
+			for _, modifier := range item.Modifiers {
+				exportedItem.Modifiers = append(exportedItem.Modifiers, modifier.Name)
+			}
+			exported.Items = append(exported.Items, exportedItem)
+		}
+		export.Orders = append(export.Orders, exported)
+	}
+	return export, nil
+}
+
+// AnonymizeUser erases the personal data of a user for good while keeping
+// their orders for accounting. Unlike deleting, it cannot be undone.
+func (h *privacyHandler) AnonymizeUser(ctx *gin.Context) {
+	intID, err := strconv.Atoi(ctx.Param("user_id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// the lockout counter is keyed by the address, which is about to go
+	user, err := h.users.GetUser(intID)
+	if err == nil {
+		err = h.throttles.ResetLoginThrottle(emailSubject(user.Email))
+	}
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	anonymized, err := h.users.AnonymizeUser(uint(intID))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "No such user in database!"})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.tokens.RevokeUserTokens(anonymized.ID); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, anonymized)
+}
@@ -1,12 +1,17 @@
 package handlers
 
 import (
+	"errors"
+	"go_final/config"
+	"go_final/media"
 	"go_final/models"
+	"go_final/money"
 	"go_final/repositories"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 type ProductHandler interface {
@@ -15,25 +20,60 @@ type ProductHandler interface {
 	CreateProduct(*gin.Context)
 	UpdateProduct(*gin.Context)
 	DeleteProduct(*gin.Context)
+	DeleteProductImage(*gin.Context)
+	CreateVariant(*gin.Context)
+	UpdateVariant(*gin.Context)
+	DeleteVariant(*gin.Context)
+	CreateModifierGroup(*gin.Context)
+	UpdateModifierGroup(*gin.Context)
+	DeleteModifierGroup(*gin.Context)
 }
 
 type productHandler struct {
-	repo repositories.ProductRepository
+	repo    repositories.ProductRepository
+	images  productImages
+	pricing config.PricingConfig
 }
 
-func NewProductHandler() ProductHandler {
+func NewProductHandler(repo repositories.ProductRepository, blobs media.BlobStore, cfg config.MediaConfig, pricing config.PricingConfig) ProductHandler {
 	return &productHandler{
-		repo: repositories.NewProductRepository(),
+		repo:    repo,
+		images:  productImages{blobs: blobs, cfg: cfg},
+		pricing: pricing,
 	}
 }
 
+// checkPrice puts a price sent as a bare amount in the shop currency and
+// rejects prices in any other currency. Deltas may be negative, prices not.
+func (h *productHandler) checkPrice(ctx *gin.Context, price *money.Money, delta bool) bool {
+	if price.Currency == "" {
+		price.Currency = h.pricing.Currency
+	}
+	if price.Currency != h.pricing.Currency {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Prices must be in " + h.pricing.Currency})
+		return false
+	}
+	if price.IsNegative() && !delta {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Price must not be negative"})
+		return false
+	}
+	return true
+}
+
 func (h *productHandler) GetAllProduct(ctx *gin.Context) {
-	product, err := h.repo.GetAllproduct()
+	query, ok := listQuery(ctx, repositories.ProductListSpec)
+	if !ok {
+		return
+	}
+	product, err := h.repo.GetAllproduct(query)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 
 	}
+	for i := range product.Items {
+		resolveImage(h.images.blobs, &product.Items[i])
+	}
 	ctx.JSON(http.StatusOK, product)
 
 }
@@ -51,30 +91,37 @@ func (h *productHandler) GetProduct(ctx *gin.Context) {
 		return
 
 	}
+	resolveImage(h.images.blobs, &product)
 	ctx.JSON(http.StatusOK, product)
 
 }
 
+// CreateProduct takes the product as JSON, or as a multipart form with its
+// picture in the "image" field.
 func (h *productHandler) CreateProduct(ctx *gin.Context) {
-	var product models.Product
-	if err := ctx.ShouldBindJSON(&product); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	product, img, ok := h.images.bindProduct(ctx)
+	if !ok || !h.checkPrice(ctx, &product.Price, false) {
 		return
 	}
 
 	product, err := h.repo.AddProduct(product)
+	if errors.Is(err, repositories.ErrUnknownCategory) || errors.Is(err, repositories.ErrUnknownTag) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 
 	}
-	ctx.JSON(http.StatusOK, product)
-
+	h.respondWithImage(ctx, product, img)
 }
+
+// UpdateProduct takes the changes as JSON, or as a multipart form to also
+// replace the picture.
 func (h *productHandler) UpdateProduct(ctx *gin.Context) {
-	var product models.Product
-	if err := ctx.ShouldBindJSON(&product); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	product, img, ok := h.images.bindProduct(ctx)
+	if !ok || !h.checkPrice(ctx, &product.Price, false) {
 		return
 	}
 
@@ -87,13 +134,36 @@ func (h *productHandler) UpdateProduct(ctx *gin.Context) {
 
 	product.ID = uint(prodID)
 	product, err = h.repo.UpdateProduct(product)
+	if errors.Is(err, repositories.ErrUnknownCategory) || errors.Is(err, repositories.ErrUnknownTag) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	h.respondWithImage(ctx, product, img)
+}
+
+// respondWithImage stores the uploaded picture of product, if any, and
+// answers with the product.
+func (h *productHandler) respondWithImage(ctx *gin.Context, product models.Product, img *media.Image) {
+	if img != nil {
+		if err := h.images.store(h.repo, product.ID, *img); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		var err error
+		if product, err = h.repo.Getproduct(int(product.ID)); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	resolveImage(h.images.blobs, &product)
 	ctx.JSON(http.StatusOK, product)
 }
+
 func (h *productHandler) DeleteProduct(ctx *gin.Context) {
 
 	var product models.Product
@@ -106,6 +176,28 @@ func (h *productHandler) DeleteProduct(ctx *gin.Context) {
 		return
 
 	}
+	h.images.remove(product.Image)
+	product.Image = nil
 	ctx.JSON(http.StatusOK, product)
 
 }
+
+func (h *productHandler) DeleteProductImage(ctx *gin.Context) {
+	prodID, err := strconv.Atoi(ctx.Param("product_id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	image, err := h.repo.DeleteProductImage(uint(prodID))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Product has no image"})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	h.images.remove(&image)
+	ctx.Status(http.StatusNoContent)
+}
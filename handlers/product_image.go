@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"go_final/config"
+	"go_final/media"
+	"go_final/models"
+	"go_final/repositories"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+// productImages stores the pictures of products in a blob store.
+type productImages struct {
+	blobs media.BlobStore
+	cfg   config.MediaConfig
+}
+
+// bindProduct reads a product from a JSON body, or from a multipart form
+// when an image is uploaded along with it. It answers the error itself; the
+// image is nil when none was uploaded.
+func (p productImages) bindProduct(ctx *gin.Context) (models.Product, *media.Image, bool) {
+	var product models.Product
+	if ctx.ContentType() != gin.MIMEMultipartPOSTForm {
+		if err := ctx.ShouldBindJSON(&product); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return product, nil, false
+		}
+		return product, nil, true
+	}
+
+	// the other fields of the form are small, a megabyte is plenty for them
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, int64(p.cfg.MaxUploadSize)+1<<20)
+	if err := ctx.ShouldBind(&product); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			ctx.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("Image must not exceed %d bytes", p.cfg.MaxUploadSize)})
+			return product, nil, false
+		}
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return product, nil, false
+	}
+
+	header, err := ctx.FormFile("image")
+	if errors.Is(err, http.ErrMissingFile) {
+		return product, nil, true
+	}
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return product, nil, false
+	}
+	if header.Size > int64(p.cfg.MaxUploadSize) {
+		ctx.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("Image must not exceed %d bytes", p.cfg.MaxUploadSize)})
+		return product, nil, false
+	}
+	file, err := header.Open()
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return product, nil, false
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return product, nil, false
+	}
+
+	img, err := media.ProcessImage(data, p.cfg.ThumbnailWidths)
+	switch {
+	case errors.Is(err, media.ErrUnsupportedImage):
+		ctx.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+		return product, nil, false
+	case errors.Is(err, media.ErrImageTooLarge):
+		ctx.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+		return product, nil, false
+	case err != nil:
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return product, nil, false
+	}
+	return product, &img, true
+}
+
+// store writes the files of img, links them to the product and deletes the
+// files of the image they replace.
+func (p productImages) store(repo repositories.ProductRepository, productID uint, img media.Image) error {
+	random := make([]byte, 8)
+	if _, err := rand.Read(random); err != nil {
+		return err
+	}
+	image := models.ProductImage{
+		ProductID:   productID,
+		Key:         fmt.Sprintf("products/%d/%s", productID, hex.EncodeToString(random)),
+		ContentType: img.ContentType,
+		Width:       img.Width,
+		Height:      img.Height,
+	}
+	for _, width := range p.cfg.ThumbnailWidths {
+		image.Widths = append(image.Widths, strconv.Itoa(width))
+	}
+
+	files := map[string][]byte{media.OriginalName(img.ContentType): img.Original}
+	for width, data := range img.Thumbnails {
+		files[media.ThumbnailName(width, img.ContentType)] = data
+	}
+	for name, data := range files {
+		if err := p.blobs.Put(image.Key+"/"+name, bytes.NewReader(data)); err != nil {
+			p.remove(&image)
+			return err
+		}
+	}
+
+	previous, err := repo.SetProductImage(image)
+	if err != nil {
+		p.remove(&image)
+		return err
+	}
+	p.remove(previous)
+	return nil
+}
+
+// remove deletes the files of an image that is no longer linked to its
+// product, failures are only logged.
+func (p productImages) remove(image *models.ProductImage) {
+	if image == nil {
+		return
+	}
+	keys := []string{image.Key + "/" + media.OriginalName(image.ContentType)}
+	for _, width := range image.Widths {
+		if width, err := strconv.Atoi(width); err == nil {
+			keys = append(keys, image.Key+"/"+media.ThumbnailName(width, image.ContentType))
+		}
+	}
+	for _, key := range keys {
+		if err := p.blobs.Delete(key); err != nil {
+			log.Printf("Error while deleting image file %s of product %d: %v", key, image.ProductID, err)
+		}
+	}
+}
+
+// resolveImage fills in the URLs of the image of product, if it has one.
+func resolveImage(blobs media.BlobStore, product *models.Product) {
+	image := product.Image
+	if image == nil {
+		return
+	}
+	image.URL = blobs.URL(image.Key + "/" + media.OriginalName(image.ContentType))
+	image.Thumbnails = make(map[string]string, len(image.Widths))
+	for _, width := range image.Widths {
+		if w, err := strconv.Atoi(width); err == nil {
+			image.Thumbnails[width] = blobs.URL(image.Key + "/" + media.ThumbnailName(w, image.ContentType))
+		}
+	}
+}
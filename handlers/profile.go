@@ -0,0 +1,253 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"go_final/mail"
+	"go_final/models"
+	"go_final/repositories"
+	"gorm.io/gorm"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func apiUser(user models.User) models.APIUser {
+	return models.APIUser{
+		ID:              user.ID,
+		Name:            user.Name,
+		Email:           user.Email,
+		Role:            user.Role,
+		EmailVerifiedAt: user.EmailVerifiedAt,
+		CreatedAt:       user.CreatedAt,
+	}
+}
+
+// currentUser loads the full record of the signed-in caller. It writes the
+// error response itself when it fails.
+func (h *userHandler) currentUser(ctx *gin.Context) (models.User, bool) {
+	principal, ok := currentPrincipal(ctx)
+	if !ok {
+		return models.User{}, false
+	}
+	user, err := h.repo.GetByID(principal.UserID)
+	if err == nil {
+		return user, true
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "No such user in database!"})
+		return models.User{}, false
+	}
+	ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	return models.User{}, false
+}
+
+// checkCurrentPassword guards changes to the account with its password.
+// Wrong guesses count towards the sign-in lockout, so a stolen access token
+// cannot be used to find the password. It writes the error response itself
+// when it fails.
+func (h *userHandler) checkCurrentPassword(ctx *gin.Context, user models.User, plain string) bool {
+	if plain == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "current_password is required"})
+		return false
+	}
+
+	now := time.Now()
+	ip := ctx.ClientIP()
+	wait, err := h.guard.lockedFor(user.Email, ip, now)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+	if wait > 0 {
+		ctx.Header("Retry-After", strconv.Itoa(int(wait.Seconds()+1)))
+		ctx.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many failed sign-in attempts, try again later"})
+		return false
+	}
+
+	if ok, _ := h.passwords.Verify(user.Password, plain); !ok {
+		if err := h.guard.fail(user.Email, ip, now); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return false
+		}
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "Current password is incorrect"})
+		return false
+	}
+	return true
+}
+
+// emailTaken reports whether another user than userID has email.
+func (h *userHandler) emailTaken(email string, userID uint) (bool, error) {
+	other, err := h.repo.GetByEmail(email)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	return err == nil && other.ID != userID, err
+}
+
+func (h *userHandler) GetMe(ctx *gin.Context) {
+	user, ok := h.currentUser(ctx)
+	if !ok {
+		return
+	}
+	ctx.JSON(http.StatusOK, apiUser(user))
+}
+
+// UpdateMe changes the caller's name and password right away. A new email
+// address only replaces the current one once the link sent to it is
+// followed.
+func (h *userHandler) UpdateMe(ctx *gin.Context) {
+	var input models.ProfileUpdate
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	user, ok := h.currentUser(ctx)
+	if !ok {
+		return
+	}
+
+	changeEmail := input.Email != "" && !strings.EqualFold(input.Email, user.Email)
+	if (changeEmail || input.Password != "") && !h.checkCurrentPassword(ctx, user, input.CurrentPassword) {
+		return
+	}
+
+	update := models.User{Model: gorm.Model{ID: user.ID}, Name: input.Name}
+	if input.Password != "" {
+		hash, ok := h.hashPassword(ctx, input.Password)
+		if !ok {
+			return
+		}
+		update.Password = hash
+	}
+	if changeEmail {
+		taken, err := h.emailTaken(input.Email, user.ID)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if taken {
+			ctx.JSON(http.StatusConflict, gin.H{"error": "Email address is already in use"})
+			return
+		}
+	}
+
+	if update.Name != "" || update.Password != "" {
+		if _, err := h.repo.UpdateUser(update); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if update.Name != "" {
+			user.Name = update.Name
+		}
+	}
+	var messages []string
+	if update.Password != "" {
+		if err := h.tokens.RevokeUserTokens(user.ID); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		messages = append(messages, "Password changed, sign in again")
+	}
+	if changeEmail {
+		target := user
+		target.Email = input.Email
+		err := h.sendUserToken(target, models.ChangeEmailPurpose, h.account.VerificationTTL.Duration,
+			"confirm-email", "Confirm your new email address",
+			"Please confirm the new email address of your SDU Canteen account:")
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		messages = append(messages, "A confirmation link has been sent to "+input.Email)
+	}
+	if len(messages) == 0 {
+		messages = append(messages, "Profile updated")
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": strings.Join(messages, ". "), "user": apiUser(user)})
+}
+
+// DeleteMe deletes the caller's account after checking their password.
+func (h *userHandler) DeleteMe(ctx *gin.Context) {
+	var input models.PasswordConfirmation
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	user, ok := h.currentUser(ctx)
+	if !ok {
+		return
+	}
+	if !h.checkCurrentPassword(ctx, user, input.CurrentPassword) {
+		return
+	}
+
+	if err := h.tokens.RevokeUserTokens(user.ID); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if _, err := h.repo.DeleteUser(user); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+// ConfirmEmailChange switches the account to the address the change_email
+// link was sent to and tells the previous address about it.
+func (h *userHandler) ConfirmEmailChange(ctx *gin.Context) {
+	var input models.VerifyEmailRequest
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	token, err := h.tokens.ConsumeUserToken(models.ChangeEmailPurpose, hashToken(input.Token))
+	if errors.Is(err, repositories.ErrUserTokenInvalid) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Confirmation link is invalid or has expired"})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	previous, err := h.repo.GetUser(int(token.UserID))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "No such user in database!"})
+		return
+	}
+	// the address may have been registered since the link was sent
+	taken, err := h.emailTaken(token.Email, token.UserID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if taken {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "Email address is already in use"})
+		return
+	}
+
+	now := time.Now()
+	update := models.User{Model: gorm.Model{ID: token.UserID}, Email: token.Email, EmailVerifiedAt: &now}
+	if _, err := h.repo.UpdateUser(update); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	err = h.mailer.Send(mail.Message{
+		To:      previous.Email,
+		Subject: "Your email address was changed",
+		Body: fmt.Sprintf("Hello %s,\n\nThe email address of your SDU Canteen account was changed to %s. If you did not do this, contact us right away.\n",
+			previous.Name, token.Email),
+	})
+	if err != nil {
+		log.Printf("Error while notifying user %d of the email change: %v", token.UserID, err)
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Email address changed"})
+}
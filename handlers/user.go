@@ -1,63 +1,345 @@
 package handlers
 
 import (
+	"errors"
 	"github.com/gin-gonic/gin"
+	"go_final/auth"
+	"go_final/config"
+	"go_final/mail"
 	"go_final/models"
+	"go_final/password"
 	"go_final/repositories"
-	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"log"
 	"net/http"
 	"strconv"
+	"time"
 )
 
 type UserHandler interface {
 	SignInUser(*gin.Context)
+	RefreshToken(*gin.Context)
+	SignOutUser(*gin.Context)
 	CreateUser(*gin.Context)
 	GetUser(*gin.Context)
 	GetAllUsers(*gin.Context)
 	UpdateUser(*gin.Context)
+	UpdateUserRole(*gin.Context)
 	DeleteUser(*gin.Context)
+	GetRoles(*gin.Context)
+	UnlockUser(*gin.Context)
+	VerifyEmail(*gin.Context)
+	ResendVerification(*gin.Context)
+	ForgotPassword(*gin.Context)
+	ResetPassword(*gin.Context)
+	SignInMFA(*gin.Context)
+	EnrollTOTP(*gin.Context)
+	ConfirmTOTP(*gin.Context)
+	DisableTOTP(*gin.Context)
+	RegenerateRecoveryCodes(*gin.Context)
+	ResetUserMFA(*gin.Context)
+	GetMe(*gin.Context)
+	UpdateMe(*gin.Context)
+	DeleteMe(*gin.Context)
+	ConfirmEmailChange(*gin.Context)
+	GetDeletedUsers(*gin.Context)
+	RestoreUser(*gin.Context)
 }
 
 type userHandler struct {
-	repo repositories.UserRepository
+	repo    repositories.UserRepository
+	tokens  repositories.TokenRepository
+	mfa     repositories.MFARepository
+	keys    *auth.KeySet
+	jwt     config.JWTConfig
+	mailer  mail.Mailer
+	account config.AccountConfig
+	guard   *loginGuard
+
+	passwords password.Hasher
+	policy    password.Policy
+	// dummyHash is verified against when the email is unknown, so that a
+	// sign-in takes as long whether or not the account exists.
+	dummyHash string
 }
 
-func NewUserHandler() UserHandler {
+func NewUserHandler(repo repositories.UserRepository, tokens repositories.TokenRepository, throttles repositories.LoginThrottleRepository, mfa repositories.MFARepository, keys *auth.KeySet, jwt config.JWTConfig, mailer mail.Mailer, account config.AccountConfig, passwords password.Hasher, policy password.Policy) UserHandler {
+	dummyHash, _ := passwords.Hash("not a real password")
 	return &userHandler{
-		repo: repositories.NewUserRepository(),
+		repo:    repo,
+		tokens:  tokens,
+		mfa:     mfa,
+		keys:    keys,
+		jwt:     jwt,
+		mailer:  mailer,
+		account: account,
+		guard:   &loginGuard{repo: throttles, cfg: account},
+
+		passwords: passwords,
+		policy:    policy,
+		dummyHash: dummyHash,
 	}
 }
 
-func hashPassword(pass *string) {
-	bytePass := []byte(*pass)
-	hPass, _ := bcrypt.GenerateFromPassword(bytePass, bcrypt.DefaultCost)
-	*pass = string(hPass)
+// validatePassword checks plain against the password policy and writes
+// the error response when it breaks any rule.
+func (h *userHandler) validatePassword(ctx *gin.Context, plain string) bool {
+	err := h.policy.Validate(plain)
+	var policyErr *password.PolicyError
+	if errors.As(err, &policyErr) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Password does not meet the policy", "violations": policyErr.Violations})
+		return false
+	}
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	return true
 }
 
-func comparePassword(dbPass, pass string) bool {
-	return bcrypt.CompareHashAndPassword([]byte(dbPass), []byte(pass)) == nil
+// hashPassword validates and hashes plain. It writes the error response
+// itself when it fails.
+func (h *userHandler) hashPassword(ctx *gin.Context, plain string) (string, bool) {
+	if !h.validatePassword(ctx, plain) {
+		return "", false
+	}
+	hash, err := h.passwords.Hash(plain)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return "", false
+	}
+	return hash, true
 }
 
 func (h *userHandler) SignInUser(ctx *gin.Context) {
 	var user models.UserLogin
 	if err := ctx.ShouldBindJSON(&user); err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	now := time.Now()
+	ip := ctx.ClientIP()
+	wait, err := h.guard.lockedFor(user.Email, ip, now)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if wait > 0 {
+		ctx.Header("Retry-After", strconv.Itoa(int(wait.Seconds()+1)))
+		ctx.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many failed sign-in attempts, try again later"})
+		return
 	}
 
 	dbUser, err := h.repo.GetByEmail(user.Email)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	found := err == nil
+	hash := dbUser.Password
+	if !found {
+		hash = h.dummyHash
+	}
+	ok, rehash := h.passwords.Verify(hash, user.Password)
+	if !ok || !found {
+		if err := h.guard.fail(user.Email, ip, now); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
+		return
+	}
+
+	if err := h.guard.succeed(user.Email); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if rehash {
+		h.rehashPassword(dbUser.ID, user.Password)
+	}
+	if dbUser.EmailVerifiedAt == nil {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "Email address has not been verified"})
+		return
+	}
+
+	mfa, err := h.mfa.GetMFA(dbUser.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if mfa.EnabledAt != nil {
+		mfaToken, err := GenerateMFAToken(h.keys, h.jwt, dbUser.ID, dbUser.Role, h.account.MFAPendingTTL.Duration)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusOK, gin.H{
+			"mfa_required": true,
+			"mfa_token":    mfaToken,
+			"expires_in":   int(h.account.MFAPendingTTL.Seconds()),
+		})
+		return
+	}
+
+	h.signIn(ctx, dbUser.ID, dbUser.Role, false)
+}
+
+// signIn starts a new refresh token family for the user and answers with
+// the token pair.
+func (h *userHandler) signIn(ctx *gin.Context, userID uint, role models.UserRole, mfa bool) {
+	familyID, err := randomToken(16)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	tokens, err := h.issueTokens(userID, role, familyID, 0, mfa)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	tokens["msg"] = "Successfully SignedIN"
+	ctx.JSON(http.StatusOK, tokens)
+}
+
+// rehashPassword replaces a hash made with outdated settings. The sign-in
+// succeeds either way, so a failure is only logged.
+func (h *userHandler) rehashPassword(userID uint, plain string) {
+	hash, err := h.passwords.Hash(plain)
+	if err == nil {
+		_, err = h.repo.UpdateUser(models.User{Model: gorm.Model{ID: userID}, Password: hash})
+	}
+	if err != nil {
+		log.Printf("Error while rehashing the password of user %d: %v", userID, err)
+	}
+}
+
+// issueTokens signs a new access token and stores a new refresh token in the
+// given family. When rotateFrom is set that refresh token is revoked in the
+// same step. mfa records that the sign-in used a second factor.
+func (h *userHandler) issueTokens(userID uint, role models.UserRole, familyID string, rotateFrom uint, mfa bool) (gin.H, error) {
+	amr := []string{auth.AMRPassword}
+	if mfa {
+		amr = append(amr, auth.AMROTP)
+	}
+	accessToken, err := GenerateToken(h.keys, h.jwt, userID, role, amr)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	stored := models.RefreshToken{
+		UserID:    userID,
+		TokenHash: hashToken(refreshToken),
+		FamilyID:  familyID,
+		MFA:       mfa,
+		ExpiresAt: time.Now().Add(h.jwt.RefreshTTL.Duration),
+	}
+	if rotateFrom != 0 {
+		_, err = h.tokens.RotateRefreshToken(rotateFrom, stored)
+	} else {
+		_, err = h.tokens.CreateRefreshToken(stored)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return gin.H{
+		"token":         accessToken,
+		"refresh_token": refreshToken,
+		"expires_in":    int(h.jwt.TTL.Seconds()),
+	}, nil
+}
+
+func (h *userHandler) RefreshToken(ctx *gin.Context) {
+	var input models.RefreshRequest
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	stored, err := h.tokens.GetRefreshToken(hashToken(input.RefreshToken))
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+		return
+	}
+
+	// A revoked token being presented again means it was stolen or replayed,
+	// so every token rotated from the same sign-in is revoked.
+	if stored.RevokedAt != nil {
+		if err := h.tokens.RevokeRefreshTokenFamily(stored.FamilyID); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token has already been used"})
+		return
+	}
+	if time.Now().After(stored.ExpiresAt) {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token has expired"})
+		return
+	}
+
+	// the role is read again so that a demotion takes effect on refresh
+	user, err := h.repo.GetUser(int(stored.UserID))
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+		return
+	}
+
+	tokens, err := h.issueTokens(user.ID, user.Role, stored.FamilyID, stored.ID, stored.MFA)
+	if errors.Is(err, repositories.ErrTokenRevoked) {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token has already been used"})
+		return
+	}
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"msg": "No Such User Found"})
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, tokens)
+}
+
+func (h *userHandler) SignOutUser(ctx *gin.Context) {
+	var input models.LogoutRequest
+	if ctx.Request.ContentLength != 0 {
+		if err := ctx.ShouldBindJSON(&input); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	principal, ok := currentPrincipal(ctx)
+	if !ok {
 		return
 	}
+	userID := principal.UserID
 
-	if isTrue := comparePassword(dbUser.Password, user.Password); isTrue {
-		token := GenerateToken(dbUser.ID, dbUser.Role)
-		ctx.JSON(http.StatusOK, gin.H{"msg": "Successfully SignedIN", "token": token})
+	if input.All {
+		if err := h.tokens.RevokeUserTokens(userID); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.Status(http.StatusNoContent)
 		return
 	}
 
-	ctx.JSON(http.StatusInternalServerError, gin.H{"msg": "Password didn't match"})
-	return
+	if err := h.tokens.RevokeAccessToken(principal.TokenID, principal.ExpiresAt); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if input.RefreshToken != "" {
+		stored, err := h.tokens.GetRefreshToken(hashToken(input.RefreshToken))
+		if err == nil && stored.UserID == userID {
+			if err := h.tokens.RevokeRefreshTokenFamily(stored.FamilyID); err != nil {
+				ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+		}
+	}
+
+	ctx.Status(http.StatusNoContent)
 }
 
 func (h *userHandler) CreateUser(ctx *gin.Context) {
@@ -67,20 +349,28 @@ func (h *userHandler) CreateUser(ctx *gin.Context) {
 		return
 	}
 
+	hash, ok := h.hashPassword(ctx, input.Password)
+	if !ok {
+		return
+	}
 	user := models.User{
 		Name:     input.Name,
 		Email:    input.Email,
-		Password: input.Password,
+		Password: hash,
 		Role:     models.CUSTOMER_ROLE,
 	}
 
-	hashPassword(&user.Password)
 	user, err := h.repo.CreateUser(user)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	// the account exists either way, a failed email can be resent
+	if err := h.sendVerification(user); err != nil {
+		log.Printf("Error while sending verification email to user %d: %v", user.ID, err)
+	}
+
 	user.Password = ""
 	ctx.JSON(http.StatusOK, user)
 }
@@ -103,12 +393,16 @@ func (h *userHandler) GetUser(ctx *gin.Context) {
 }
 
 func (h *userHandler) GetAllUsers(ctx *gin.Context) {
-	user, err := h.repo.GetAllUsers()
+	query, ok := listQuery(ctx, repositories.UserListSpec)
+	if !ok {
+		return
+	}
+	users, err := h.repo.GetAllUsers(query)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	ctx.JSON(http.StatusOK, user)
+	ctx.JSON(http.StatusOK, users)
 }
 
 func (h *userHandler) UpdateUser(ctx *gin.Context) {
@@ -137,8 +431,11 @@ func (h *userHandler) UpdateUser(ctx *gin.Context) {
 	}
 	passwordChanged := false
 	if input.Password != "" {
-		existingUser.Password = input.Password
-		hashPassword(&existingUser.Password)
+		hash, ok := h.hashPassword(ctx, input.Password)
+		if !ok {
+			return
+		}
+		existingUser.Password = hash
 		passwordChanged = true
 	}
 
@@ -148,6 +445,13 @@ func (h *userHandler) UpdateUser(ctx *gin.Context) {
 		return
 	}
 
+	if passwordChanged {
+		if err := h.tokens.RevokeUserTokens(updatedUser.ID); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
 	user := models.APIUser{
 		ID:    updatedUser.ID,
 		Name:  updatedUser.Name,
@@ -162,6 +466,8 @@ func (h *userHandler) UpdateUser(ctx *gin.Context) {
 	}
 }
 
+// DeleteUser soft deletes the user, RestoreUser brings them back. The user
+// is signed out everywhere.
 func (h *userHandler) DeleteUser(ctx *gin.Context) {
 	var user models.User
 	id := ctx.Param("user_id")
@@ -169,10 +475,115 @@ func (h *userHandler) DeleteUser(ctx *gin.Context) {
 	user.ID = uint(intID)
 
 	user, err := h.repo.DeleteUser(user)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "No such user in database!"})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.tokens.RevokeUserTokens(user.ID); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, apiUser(user))
+}
+
+func (h *userHandler) GetDeletedUsers(ctx *gin.Context) {
+	users, err := h.repo.GetDeletedUsers()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, users)
+}
+
+func (h *userHandler) RestoreUser(ctx *gin.Context) {
+	intID, err := strconv.Atoi(ctx.Param("user_id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.repo.RestoreUser(uint(intID))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "No such deleted user in database!"})
+		return
+	}
+	if errors.Is(err, repositories.ErrUserAnonymized) {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "User has been anonymized and cannot be restored"})
+		return
+	}
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	ctx.JSON(http.StatusOK, user)
+}
+
+func (h *userHandler) UpdateUserRole(ctx *gin.Context) {
+	var input models.UserRoleUpdate
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !auth.IsKnownRole(input.Role) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Unknown role: " + string(input.Role)})
+		return
+	}
+
+	intID, err := strconv.Atoi(ctx.Param("user_id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.repo.GetUser(intID)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "No such user in database!"})
+		return
+	}
+
+	if user.Role != input.Role {
+		if _, err := h.repo.UpdateUser(models.User{Model: gorm.Model{ID: user.ID}, Role: input.Role}); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		// tokens carry the role, so the old ones must not outlive the change
+		if err := h.tokens.RevokeUserTokens(user.ID); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		user.Role = input.Role
+	}
 
 	ctx.JSON(http.StatusOK, user)
 }
+
+func (h *userHandler) GetRoles(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, auth.Roles())
+}
+
+// UnlockUser clears the failed sign-ins of the user's email address. A
+// lockout of the IP the attempts came from is left to expire.
+func (h *userHandler) UnlockUser(ctx *gin.Context) {
+	intID, err := strconv.Atoi(ctx.Param("user_id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.repo.GetUser(intID)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "No such user in database!"})
+		return
+	}
+
+	if err := h.guard.unlock(user.Email); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
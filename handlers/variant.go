@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"go_final/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (h *productHandler) CreateVariant(ctx *gin.Context) {
+	productID, ok := idParam(ctx, "product_id")
+	if !ok {
+		return
+	}
+	var variant models.ProductVariant
+	if err := ctx.ShouldBindJSON(&variant); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !h.checkPrice(ctx, &variant.Price, false) {
+		return
+	}
+	variant.ID = 0
+	variant.ProductID = productID
+
+	variant, err := h.repo.AddVariant(variant)
+	if err != nil {
+		catalogError(ctx, err, "product")
+		return
+	}
+	ctx.JSON(http.StatusOK, variant)
+}
+
+func (h *productHandler) UpdateVariant(ctx *gin.Context) {
+	productID, ok := idParam(ctx, "product_id")
+	if !ok {
+		return
+	}
+	variantID, ok := idParam(ctx, "variant_id")
+	if !ok {
+		return
+	}
+	var variant models.ProductVariant
+	if err := ctx.ShouldBindJSON(&variant); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !h.checkPrice(ctx, &variant.Price, false) {
+		return
+	}
+	variant.ID = variantID
+	variant.ProductID = productID
+
+	variant, err := h.repo.UpdateVariant(variant)
+	if err != nil {
+		catalogError(ctx, err, "variant")
+		return
+	}
+	ctx.JSON(http.StatusOK, variant)
+}
+
+// DeleteVariant removes a variant, orders keep its name and price.
+func (h *productHandler) DeleteVariant(ctx *gin.Context) {
+	productID, ok := idParam(ctx, "product_id")
+	if !ok {
+		return
+	}
+	variantID, ok := idParam(ctx, "variant_id")
+	if !ok {
+		return
+	}
+	if err := h.repo.DeleteVariant(productID, variantID); err != nil {
+		catalogError(ctx, err, "variant")
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+func (h *productHandler) CreateModifierGroup(ctx *gin.Context) {
+	productID, ok := idParam(ctx, "product_id")
+	if !ok {
+		return
+	}
+	var group models.ModifierGroup
+	if err := ctx.ShouldBindJSON(&group); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	for i := range group.Options {
+		if !h.checkPrice(ctx, &group.Options[i].PriceDelta, true) {
+			return
+		}
+	}
+	group.ID = 0
+	group.ProductID = productID
+	for i := range group.Options {
+		group.Options[i].ID = 0
+	}
+
+	group, err := h.repo.AddModifierGroup(group)
+	if err != nil {
+		catalogError(ctx, err, "product")
+		return
+	}
+	ctx.JSON(http.StatusOK, group)
+}
+
+// UpdateModifierGroup replaces the group with its options, the options get
+// new ids.
+func (h *productHandler) UpdateModifierGroup(ctx *gin.Context) {
+	productID, ok := idParam(ctx, "product_id")
+	if !ok {
+		return
+	}
+	groupID, ok := idParam(ctx, "group_id")
+	if !ok {
+		return
+	}
+	var group models.ModifierGroup
+	if err := ctx.ShouldBindJSON(&group); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	for i := range group.Options {
+		if !h.checkPrice(ctx, &group.Options[i].PriceDelta, true) {
+			return
+		}
+	}
+	group.ID = groupID
+	group.ProductID = productID
+
+	group, err := h.repo.UpdateModifierGroup(group)
+	if err != nil {
+		catalogError(ctx, err, "modifier group")
+		return
+	}
+	ctx.JSON(http.StatusOK, group)
+}
+
+func (h *productHandler) DeleteModifierGroup(ctx *gin.Context) {
+	productID, ok := idParam(ctx, "product_id")
+	if !ok {
+		return
+	}
+	groupID, ok := idParam(ctx, "group_id")
+	if !ok {
+		return
+	}
+	if err := h.repo.DeleteModifierGroup(productID, groupID); err != nil {
+		catalogError(ctx, err, "modifier group")
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
@@ -1,18 +1,123 @@
 package main
 
 import (
-	"github.com/joho/godotenv"
+	"errors"
+	"fmt"
+	"go_final/app"
+	"go_final/auth"
+	"go_final/config"
+	"go_final/migrations"
+	"go_final/repositories"
 	"go_final/route"
 	"log"
+	"os"
 )
 
-func loadenv() {
-	if err := godotenv.Load(); err != nil {
-		log.Fatal("Error while loading .env file: " + err.Error())
+const (
+	migrateUsage = "usage: myapp migrate up|down|status"
+	keygenUsage  = "usage: myapp keygen RS256|EdDSA <dir>"
+)
+
+func keygen(args []string) error {
+	if len(args) != 2 {
+		return errors.New(keygenUsage)
+	}
+	kid, err := auth.GenerateKey(args[0], args[1])
+	if err != nil {
+		return err
+	}
+	fmt.Printf("generated key %s, set JWT_ACTIVE_KEY_ID=%s to sign with it\n", kid, kid)
+	return nil
+}
+
+func migrate(cfg *config.Config, args []string) error {
+	if len(args) != 1 {
+		return errors.New(migrateUsage)
+	}
+
+	store, err := repositories.NewStore(cfg.Database, cfg.Pricing)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	sqlStore, ok := store.(repositories.SQLStore)
+	if !ok {
+		return errors.New("the selected storage driver has no schema to migrate")
+	}
+	vars, err := migrations.PricingVars(cfg.Pricing)
+	if err != nil {
+		return err
+	}
+	migrator, err := migrations.New(sqlStore.DB(), vars)
+	if err != nil {
+		return err
 	}
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up()
+		for _, m := range applied {
+			fmt.Printf("applied %04d_%s\n", m.Version, m.Name)
+		}
+		if err == nil && len(applied) == 0 {
+			fmt.Println("no pending migrations")
+		}
+		return err
+	case "down":
+		m, err := migrator.Down()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("rolled back %04d_%s\n", m.Version, m.Name)
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			return err
+		}
+		for _, s := range statuses {
+			applied := "pending"
+			if s.AppliedAt != nil {
+				applied = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%s\t%s\n", s.Version, s.Name, applied)
+		}
+	default:
+		return errors.New(migrateUsage)
+	}
+	return nil
 }
 
 func main() {
-	// loadenv()
-	log.Fatal(route.RunAPI(":8080"))
+	if len(os.Args) > 1 && os.Args[1] == "keygen" {
+		if err := keygen(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := migrate(cfg, os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	c, err := app.Open(cfg)
+	if err != nil {
+		log.Fatal("Error while starting application: " + err.Error())
+	}
+
+	runErr := route.RunAPI(c)
+	if err := c.Close(); err != nil {
+		log.Println("Error while closing storage: " + err.Error())
+	}
+	if runErr != nil {
+		log.Fatal(runErr)
+	}
 }
@@ -0,0 +1,156 @@
+package media
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+)
+
+// maxPixels guards against small files that decode to huge images.
+const maxPixels = 40_000_000
+
+var (
+	ErrUnsupportedImage = errors.New("unsupported image type, upload a JPEG, PNG or GIF")
+	ErrInvalidImage     = errors.New("image cannot be decoded")
+	ErrImageTooLarge    = fmt.Errorf("image has more than %d pixels", maxPixels)
+)
+
+// extensions of the accepted content types, which are sniffed from the
+// data rather than taken from the client.
+var extensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+}
+
+// Image is a validated upload together with its thumbnails.
+type Image struct {
+	ContentType string
+	Width       int
+	Height      int
+	Original    []byte
+	// Thumbnails are keyed by their width. Images narrower than a width
+	// are not enlarged.
+	Thumbnails map[int][]byte
+}
+
+// OriginalName is the file name of the upload within the key of an image.
+func OriginalName(contentType string) string {
+	return "original" + extensions[contentType]
+}
+
+// ThumbnailName is the file name of the thumbnail of the given width. JPEGs
+// are scaled to JPEGs, everything else to PNGs.
+func ThumbnailName(width int, contentType string) string {
+	if contentType == "image/jpeg" {
+		return fmt.Sprintf("w%d.jpg", width)
+	}
+	return fmt.Sprintf("w%d.png", width)
+}
+
+// ThumbnailContentType is the content type of the thumbnails of an image.
+func ThumbnailContentType(contentType string) string {
+	if contentType == "image/jpeg" {
+		return contentType
+	}
+	return "image/png"
+}
+
+// ProcessImage validates an uploaded image and scales it to each of widths.
+func ProcessImage(data []byte, widths []int) (Image, error) {
+	contentType := http.DetectContentType(data)
+	if _, ok := extensions[contentType]; !ok {
+		return Image{}, ErrUnsupportedImage
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || config.Width <= 0 || config.Height <= 0 {
+		return Image{}, ErrInvalidImage
+	}
+	if config.Width*config.Height > maxPixels {
+		return Image{}, ErrImageTooLarge
+	}
+	decoded, err := decode(contentType, data)
+	if err != nil {
+		return Image{}, ErrInvalidImage
+	}
+
+	bounds := decoded.Bounds()
+	src := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(src, src.Bounds(), decoded, bounds.Min, draw.Src)
+
+	img := Image{
+		ContentType: contentType,
+		Width:       bounds.Dx(),
+		Height:      bounds.Dy(),
+		Original:    data,
+		Thumbnails:  make(map[int][]byte, len(widths)),
+	}
+	for _, width := range widths {
+		var buf bytes.Buffer
+		thumbnail := scale(src, width)
+		if contentType == "image/jpeg" {
+			err = jpeg.Encode(&buf, thumbnail, &jpeg.Options{Quality: 85})
+		} else {
+			err = png.Encode(&buf, thumbnail)
+		}
+		if err != nil {
+			return Image{}, err
+		}
+		img.Thumbnails[width] = buf.Bytes()
+	}
+	return img, nil
+}
+
+func decode(contentType string, data []byte) (image.Image, error) {
+	switch contentType {
+	case "image/jpeg":
+		return jpeg.Decode(bytes.NewReader(data))
+	case "image/png":
+		return png.Decode(bytes.NewReader(data))
+	default:
+		return gif.Decode(bytes.NewReader(data))
+	}
+}
+
+// scale shrinks src to width pixels keeping its aspect ratio. Every pixel
+// of the result is the average of the block of source pixels it covers.
+func scale(src *image.RGBA, width int) *image.RGBA {
+	srcWidth, srcHeight := src.Rect.Dx(), src.Rect.Dy()
+	if width > srcWidth {
+		width = srcWidth
+	}
+	height := max(1, srcHeight*width/srcWidth)
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0 := y * srcHeight / height
+		y1 := max((y+1)*srcHeight/height, y0+1)
+		for x := 0; x < width; x++ {
+			x0 := x * srcWidth / width
+			x1 := max((x+1)*srcWidth/width, x0+1)
+
+			var sum [4]uint64
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[src.PixOffset(x0, sy):src.PixOffset(x1, sy)]
+				for i := 0; i < len(row); i += 4 {
+					sum[0] += uint64(row[i])
+					sum[1] += uint64(row[i+1])
+					sum[2] += uint64(row[i+2])
+					sum[3] += uint64(row[i+3])
+				}
+			}
+			n := uint64((x1 - x0) * (y1 - y0))
+			offset := dst.PixOffset(x, y)
+			for i := range sum {
+				dst.Pix[offset+i] = uint8(sum[i] / n)
+			}
+		}
+	}
+	return dst
+}
@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"errors"
+	"go_final/auth"
+	"go_final/repositories"
+	"log"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const APIKeyHeader = "X-API-Key"
+
+// APIKey authenticates integrations by the key in the X-API-Key header. The
+// caller holds the permissions of the key and no role.
+func APIKey(keys repositories.APIKeyRepository) Authenticator {
+	return func(ctx *gin.Context) (auth.Principal, error) {
+		key := ctx.GetHeader(APIKeyHeader)
+		if key == "" {
+			return auth.Principal{}, errNoCredentials
+		}
+
+		stored, err := keys.GetAPIKeyByHash(auth.HashAPIKey(key))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return auth.Principal{}, unauthorized("Invalid API key")
+		}
+		if err != nil {
+			return auth.Principal{}, err
+		}
+		now := time.Now()
+		if stored.RevokedAt != nil {
+			return auth.Principal{}, unauthorized("API key has been revoked")
+		}
+		if stored.ExpiresAt != nil && now.After(*stored.ExpiresAt) {
+			return auth.Principal{}, unauthorized("API key has expired")
+		}
+
+		// the request is authenticated either way
+		if err := keys.TouchAPIKey(stored.ID, now); err != nil {
+			log.Printf("Error while recording the use of API key %d: %v", stored.ID, err)
+		}
+
+		principal := auth.Principal{APIKeyID: stored.ID}
+		if stored.ExpiresAt != nil {
+			principal.ExpiresAt = *stored.ExpiresAt
+		}
+		for _, permission := range stored.Permissions {
+			// keys created before a permission was withdrawn from keys lose it
+			if auth.IsGrantableToAPIKey(auth.Permission(permission)) {
+				principal.Permissions = append(principal.Permissions, auth.Permission(permission))
+			}
+		}
+		return principal, nil
+	}
+}
@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"errors"
+	"go_final/auth"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Authenticator identifies the caller of a request from one kind of
+// credential. It returns errNoCredentials when the request carries none of
+// its kind, so that the next authenticator of the chain is tried.
+type Authenticator func(*gin.Context) (auth.Principal, error)
+
+var errNoCredentials = errors.New("no credentials")
+
+// authError is a rejected credential, answered with its status and message.
+type authError struct {
+	status  int
+	message string
+}
+
+func (e *authError) Error() string {
+	return e.message
+}
+
+func unauthorized(message string) error {
+	return &authError{status: http.StatusUnauthorized, message: message}
+}
+
+// Authenticate stores the principal found by the first authenticator whose
+// credentials the request carries. Requests without any are rejected.
+func Authenticate(authenticators ...Authenticator) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		for _, authenticate := range authenticators {
+			principal, err := authenticate(ctx)
+			if errors.Is(err, errNoCredentials) {
+				continue
+			}
+			var authErr *authError
+			if errors.As(err, &authErr) {
+				ctx.AbortWithStatusJSON(authErr.status, gin.H{"error": authErr.message})
+				return
+			}
+			if err != nil {
+				ctx.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+
+			auth.SetPrincipal(ctx, principal)
+			ctx.Next()
+			return
+		}
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "No Authorization header found"})
+	}
+}
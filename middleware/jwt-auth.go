@@ -1,41 +1,54 @@
 package middleware
 
 import (
+	"go_final/auth"
+	"go_final/config"
 	"go_final/handlers"
-	"net/http"
+	"go_final/repositories"
+	"strings"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
 )
 
-func AuthorizeJWT() gin.HandlerFunc {
-	return func(ctx *gin.Context) {
+// AuthorizeJWT only accepts user access tokens, for routes that act on the
+// session of a user.
+func AuthorizeJWT(keys *auth.KeySet, cfg config.JWTConfig, tokens repositories.TokenRepository) gin.HandlerFunc {
+	return Authenticate(BearerJWT(keys, cfg, tokens))
+}
+
+// BearerJWT authenticates users by the access token in the Authorization
+// header.
+func BearerJWT(keys *auth.KeySet, cfg config.JWTConfig, tokens repositories.TokenRepository) Authenticator {
+	return func(ctx *gin.Context) (auth.Principal, error) {
 		const BearerSchema string = "Bearer "
 		authHeader := ctx.GetHeader("Authorization")
 		if authHeader == "" {
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
-				"error": "No Authorization header found"})
+			return auth.Principal{}, errNoCredentials
+		}
+		if !strings.HasPrefix(authHeader, BearerSchema) {
+			return auth.Principal{}, unauthorized("Not Valid Token")
 		}
 
-		tokenString := authHeader[len(BearerSchema):]
-		if token, err := handlers.ValidateToken(tokenString); err != nil {
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
-				"error": "Not Valid Token"})
-
-		} else {
-			if claims, ok := token.Claims.(jwt.MapClaims); !ok {
-				ctx.AbortWithStatus(http.StatusUnauthorized)
-
-			} else {
-				if token.Valid {
-					ctx.Set("userID", claims["userID"])
-					ctx.Set("role", claims["role"])
-					ctx.Next()
+		claims, err := handlers.ValidateToken(keys, cfg, authHeader[len(BearerSchema):])
+		if err != nil {
+			return auth.Principal{}, unauthorized("Not Valid Token")
+		}
+		userID, _ := claims.UserID()
 
-				} else {
-					ctx.AbortWithStatus(http.StatusUnauthorized)
-				}
-			}
+		revoked, err := tokens.IsAccessTokenRevoked(claims.ID, userID, claims.IssuedAtTime())
+		if err != nil {
+			return auth.Principal{}, err
 		}
+		if revoked {
+			return auth.Principal{}, unauthorized("Token has been revoked")
+		}
+
+		return auth.Principal{
+			UserID:    userID,
+			Role:      claims.Role,
+			TokenID:   claims.ID,
+			ExpiresAt: claims.ExpiresAt.Time,
+			MFA:       claims.HasAMR(auth.AMROTP),
+		}, nil
 	}
 }
@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"go_final/auth"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireMFA rejects callers whose role is listed in roles unless they
+// signed in with a second factor.
+func RequireMFA(roles []string) gin.HandlerFunc {
+	required := make(map[string]bool, len(roles))
+	for _, role := range roles {
+		required[role] = true
+	}
+	return func(ctx *gin.Context) {
+		principal, ok := auth.CurrentPrincipal(ctx)
+		if !ok {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+			return
+		}
+		if required[string(principal.Role)] && !principal.MFA {
+			ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error": "Two-factor authentication is required for your role"})
+			return
+		}
+		ctx.Next()
+	}
+}
@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"go_final/auth"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequirePermission lets the request through only if the authenticated
+// caller holds every one of the given permissions.
+func RequirePermission(permissions ...auth.Permission) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		principal, ok := auth.CurrentPrincipal(ctx)
+		if !ok {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+			return
+		}
+
+		for _, permission := range permissions {
+			if !principal.Has(permission) {
+				ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+					"error": "Missing permission: " + string(permission)})
+				return
+			}
+		}
+		ctx.Next()
+	}
+}
+
+// ResourceResolver identifies the resource a request acts on, usually from
+// its path parameters. It writes the error response itself when it fails.
+type ResourceResolver func(*gin.Context) (auth.Resource, bool)
+
+// Authorize asks the policy engine whether the caller may perform action on
+// the resource picked by resolve.
+func Authorize(action auth.Action, resolve ResourceResolver) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		principal, ok := auth.CurrentPrincipal(ctx)
+		if !ok {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+			return
+		}
+
+		resource, ok := resolve(ctx)
+		if !ok {
+			return
+		}
+		if !auth.Can(principal, action, resource) {
+			ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error": "Not allowed to " + string(action) + " this " + string(resource.Kind)})
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// UserParam resolves the user named by the :user_id path parameter.
+func UserParam(ctx *gin.Context) (auth.Resource, bool) {
+	id, err := strconv.ParseUint(ctx.Param("user_id"), 10, 64)
+	if err != nil {
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return auth.Resource{}, false
+	}
+	return auth.User(uint(id)), true
+}
+
+// AnyProduct resolves to the product resource, which has no owner.
+func AnyProduct(*gin.Context) (auth.Resource, bool) {
+	return auth.Product(), true
+}
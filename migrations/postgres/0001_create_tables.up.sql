@@ -0,0 +1,46 @@
+CREATE TABLE IF NOT EXISTS users (
+    id bigserial PRIMARY KEY,
+    created_at timestamp with time zone,
+    updated_at timestamp with time zone,
+    deleted_at timestamp with time zone,
+    name text,
+    email text CONSTRAINT uni_users_email UNIQUE,
+    password text,
+    role character varying(100) NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users (deleted_at);
+
+CREATE TABLE IF NOT EXISTS products (
+    id bigserial PRIMARY KEY,
+    created_at timestamp with time zone,
+    updated_at timestamp with time zone,
+    deleted_at timestamp with time zone,
+    name text CONSTRAINT uni_products_name UNIQUE,
+    quantity bigint,
+    description text,
+    price bigint
+);
+CREATE INDEX IF NOT EXISTS idx_products_deleted_at ON products (deleted_at);
+
+CREATE TABLE IF NOT EXISTS orders (
+    id bigserial PRIMARY KEY,
+    created_at timestamp with time zone,
+    updated_at timestamp with time zone,
+    deleted_at timestamp with time zone,
+    user_id bigint CONSTRAINT fk_orders_user REFERENCES users (id) ON UPDATE CASCADE ON DELETE SET NULL,
+    order_status character varying(100) NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_orders_deleted_at ON orders (deleted_at);
+
+CREATE TABLE IF NOT EXISTS order_items (
+    id bigserial PRIMARY KEY,
+    created_at timestamp with time zone,
+    updated_at timestamp with time zone,
+    deleted_at timestamp with time zone,
+    order_id bigint CONSTRAINT fk_order_items_order REFERENCES orders (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    product_id bigint CONSTRAINT fk_order_items_product REFERENCES products (id) ON UPDATE CASCADE ON DELETE SET NULL,
+    quantity bigint,
+    price bigint
+);
+CREATE INDEX IF NOT EXISTS idx_order_items_deleted_at ON order_items (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_order_items_order_id ON order_items (order_id);
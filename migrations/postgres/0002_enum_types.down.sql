@@ -0,0 +1,5 @@
+ALTER TABLE users ALTER COLUMN role TYPE character varying(100) USING role::text;
+ALTER TABLE orders ALTER COLUMN order_status TYPE character varying(100) USING order_status::text;
+
+DROP TYPE IF EXISTS user_role_type;
+DROP TYPE IF EXISTS order_status_type;
@@ -0,0 +1,26 @@
+-- The types already exist in databases restored from db_dump.sql.
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'order_status_type') THEN
+        CREATE TYPE order_status_type AS ENUM (
+            'pending',
+            'accepted',
+            'ready',
+            'out',
+            'delivered',
+            'confirmed',
+            'canceled'
+        );
+    END IF;
+
+    IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'user_role_type') THEN
+        CREATE TYPE user_role_type AS ENUM (
+            'admin',
+            'customer'
+        );
+    END IF;
+END
+$$;
+
+ALTER TABLE orders ALTER COLUMN order_status TYPE order_status_type USING order_status::order_status_type;
+ALTER TABLE users ALTER COLUMN role TYPE user_role_type USING role::user_role_type;
@@ -0,0 +1,11 @@
+-- Enum values cannot be dropped, so the type is rebuilt without them.
+ALTER TABLE users ALTER COLUMN role TYPE character varying(100) USING role::text;
+UPDATE users SET role = 'customer' WHERE role IN ('cashier', 'cook', 'courier');
+
+DROP TYPE user_role_type;
+CREATE TYPE user_role_type AS ENUM (
+    'admin',
+    'customer'
+);
+
+ALTER TABLE users ALTER COLUMN role TYPE user_role_type USING role::user_role_type;
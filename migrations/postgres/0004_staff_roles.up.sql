@@ -0,0 +1,3 @@
+ALTER TYPE user_role_type ADD VALUE IF NOT EXISTS 'cashier';
+ALTER TYPE user_role_type ADD VALUE IF NOT EXISTS 'cook';
+ALTER TYPE user_role_type ADD VALUE IF NOT EXISTS 'courier';
@@ -0,0 +1,18 @@
+ALTER TABLE users ADD COLUMN email_verified_at timestamp with time zone;
+-- Accounts created before verification existed stay usable.
+UPDATE users SET email_verified_at = COALESCE(created_at, now());
+
+CREATE TABLE user_tokens (
+    id bigserial PRIMARY KEY,
+    created_at timestamp with time zone,
+    updated_at timestamp with time zone,
+    deleted_at timestamp with time zone,
+    user_id bigint NOT NULL REFERENCES users (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    purpose character varying(32) NOT NULL,
+    token_hash text NOT NULL,
+    expires_at timestamp with time zone NOT NULL,
+    used_at timestamp with time zone
+);
+CREATE UNIQUE INDEX idx_user_tokens_token_hash ON user_tokens (token_hash);
+CREATE INDEX idx_user_tokens_user_id ON user_tokens (user_id);
+CREATE INDEX idx_user_tokens_deleted_at ON user_tokens (deleted_at);
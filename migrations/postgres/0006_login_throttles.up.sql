@@ -0,0 +1,6 @@
+CREATE TABLE login_throttles (
+    subject text PRIMARY KEY,
+    failures bigint NOT NULL,
+    last_failure_at timestamp with time zone,
+    locked_until timestamp with time zone
+);
@@ -0,0 +1,19 @@
+CREATE TABLE user_mfa (
+    user_id bigint PRIMARY KEY REFERENCES users (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    totp_secret text NOT NULL,
+    enabled_at timestamp with time zone,
+    last_step bigint NOT NULL DEFAULT 0,
+    created_at timestamp with time zone,
+    updated_at timestamp with time zone
+);
+
+CREATE TABLE recovery_codes (
+    id bigserial PRIMARY KEY,
+    user_id bigint NOT NULL REFERENCES users (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    code_hash text NOT NULL,
+    used_at timestamp with time zone,
+    created_at timestamp with time zone
+);
+CREATE INDEX idx_recovery_codes_user_id ON recovery_codes (user_id);
+
+ALTER TABLE refresh_tokens ADD COLUMN mfa boolean NOT NULL DEFAULT false;
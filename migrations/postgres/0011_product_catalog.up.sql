@@ -0,0 +1,33 @@
+CREATE TABLE categories (
+    id bigserial PRIMARY KEY,
+    created_at timestamp with time zone,
+    updated_at timestamp with time zone,
+    deleted_at timestamp with time zone,
+    name text NOT NULL CONSTRAINT uni_categories_name UNIQUE,
+    description text,
+    position bigint NOT NULL DEFAULT 0
+);
+CREATE INDEX idx_categories_deleted_at ON categories (deleted_at);
+
+CREATE TABLE tags (
+    id bigserial PRIMARY KEY,
+    created_at timestamp with time zone,
+    updated_at timestamp with time zone,
+    deleted_at timestamp with time zone,
+    name text NOT NULL CONSTRAINT uni_tags_name UNIQUE
+);
+CREATE INDEX idx_tags_deleted_at ON tags (deleted_at);
+
+CREATE TABLE product_categories (
+    product_id bigint NOT NULL CONSTRAINT fk_product_categories_product REFERENCES products (id) ON DELETE CASCADE,
+    category_id bigint NOT NULL CONSTRAINT fk_product_categories_category REFERENCES categories (id) ON DELETE CASCADE,
+    PRIMARY KEY (product_id, category_id)
+);
+CREATE INDEX idx_product_categories_category_id ON product_categories (category_id);
+
+CREATE TABLE product_tags (
+    product_id bigint NOT NULL CONSTRAINT fk_product_tags_product REFERENCES products (id) ON DELETE CASCADE,
+    tag_id bigint NOT NULL CONSTRAINT fk_product_tags_tag REFERENCES tags (id) ON DELETE CASCADE,
+    PRIMARY KEY (product_id, tag_id)
+);
+CREATE INDEX idx_product_tags_tag_id ON product_tags (tag_id);
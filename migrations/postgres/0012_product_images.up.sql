@@ -0,0 +1,9 @@
+CREATE TABLE product_images (
+    product_id bigint PRIMARY KEY CONSTRAINT fk_products_image REFERENCES products (id) ON DELETE CASCADE,
+    key text NOT NULL,
+    content_type text NOT NULL,
+    width bigint,
+    height bigint,
+    widths text NOT NULL,
+    created_at timestamp with time zone
+);
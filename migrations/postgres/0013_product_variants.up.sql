@@ -0,0 +1,52 @@
+CREATE TABLE product_variants (
+    id bigserial PRIMARY KEY,
+    created_at timestamp with time zone,
+    updated_at timestamp with time zone,
+    deleted_at timestamp with time zone,
+    product_id bigint NOT NULL CONSTRAINT fk_products_variants REFERENCES products (id) ON DELETE CASCADE,
+    name text NOT NULL,
+    price bigint NOT NULL DEFAULT 0,
+    quantity bigint NOT NULL DEFAULT 0,
+    position bigint NOT NULL DEFAULT 0
+);
+CREATE INDEX idx_product_variants_deleted_at ON product_variants (deleted_at);
+CREATE INDEX idx_product_variants_product_id ON product_variants (product_id);
+
+CREATE TABLE modifier_groups (
+    id bigserial PRIMARY KEY,
+    created_at timestamp with time zone,
+    updated_at timestamp with time zone,
+    deleted_at timestamp with time zone,
+    product_id bigint NOT NULL CONSTRAINT fk_products_modifier_groups REFERENCES products (id) ON DELETE CASCADE,
+    name text NOT NULL,
+    min_selections bigint NOT NULL DEFAULT 0,
+    max_selections bigint NOT NULL DEFAULT 1,
+    position bigint NOT NULL DEFAULT 0
+);
+CREATE INDEX idx_modifier_groups_deleted_at ON modifier_groups (deleted_at);
+CREATE INDEX idx_modifier_groups_product_id ON modifier_groups (product_id);
+
+CREATE TABLE modifier_options (
+    id bigserial PRIMARY KEY,
+    created_at timestamp with time zone,
+    updated_at timestamp with time zone,
+    deleted_at timestamp with time zone,
+    group_id bigint NOT NULL CONSTRAINT fk_modifier_groups_options REFERENCES modifier_groups (id) ON DELETE CASCADE,
+    name text NOT NULL,
+    price_delta bigint NOT NULL DEFAULT 0,
+    position bigint NOT NULL DEFAULT 0
+);
+CREATE INDEX idx_modifier_options_deleted_at ON modifier_options (deleted_at);
+CREATE INDEX idx_modifier_options_group_id ON modifier_options (group_id);
+
+ALTER TABLE order_items ADD COLUMN variant_id bigint CONSTRAINT fk_order_items_variant REFERENCES product_variants (id) ON DELETE SET NULL;
+ALTER TABLE order_items ADD COLUMN variant_name text NOT NULL DEFAULT '';
+
+CREATE TABLE order_item_modifiers (
+    id bigserial PRIMARY KEY,
+    order_item_id bigint NOT NULL CONSTRAINT fk_order_items_modifiers REFERENCES order_items (id) ON DELETE CASCADE,
+    modifier_option_id bigint CONSTRAINT fk_order_item_modifiers_option REFERENCES modifier_options (id) ON DELETE SET NULL,
+    name text NOT NULL,
+    price_delta bigint NOT NULL DEFAULT 0
+);
+CREATE INDEX idx_order_item_modifiers_order_item_id ON order_item_modifiers (order_item_id);
@@ -0,0 +1,29 @@
+-- amounts are rounded to whole units, fractions of a unit are lost
+ALTER TABLE orders DROP COLUMN IF EXISTS total_currency;
+ALTER TABLE orders DROP COLUMN IF EXISTS total_amount;
+ALTER TABLE orders DROP COLUMN IF EXISTS tax_currency;
+ALTER TABLE orders DROP COLUMN IF EXISTS tax_amount;
+ALTER TABLE orders DROP COLUMN IF EXISTS discount_currency;
+ALTER TABLE orders DROP COLUMN IF EXISTS discount_amount;
+ALTER TABLE orders DROP COLUMN IF EXISTS subtotal_currency;
+ALTER TABLE orders DROP COLUMN IF EXISTS subtotal_amount;
+
+ALTER TABLE order_item_modifiers DROP COLUMN IF EXISTS price_delta_currency;
+UPDATE order_item_modifiers SET price_delta_amount = ROUND(price_delta_amount / {{MINOR_UNITS}}.0);
+ALTER TABLE order_item_modifiers RENAME COLUMN price_delta_amount TO price_delta;
+
+ALTER TABLE order_items DROP COLUMN IF EXISTS price_currency;
+UPDATE order_items SET price_amount = ROUND(price_amount / {{MINOR_UNITS}}.0);
+ALTER TABLE order_items RENAME COLUMN price_amount TO price;
+
+ALTER TABLE modifier_options DROP COLUMN IF EXISTS price_delta_currency;
+UPDATE modifier_options SET price_delta_amount = ROUND(price_delta_amount / {{MINOR_UNITS}}.0);
+ALTER TABLE modifier_options RENAME COLUMN price_delta_amount TO price_delta;
+
+ALTER TABLE product_variants DROP COLUMN IF EXISTS price_currency;
+UPDATE product_variants SET price_amount = ROUND(price_amount / {{MINOR_UNITS}}.0);
+ALTER TABLE product_variants RENAME COLUMN price_amount TO price;
+
+ALTER TABLE products DROP COLUMN IF EXISTS price_currency;
+UPDATE products SET price_amount = ROUND(price_amount / {{MINOR_UNITS}}.0);
+ALTER TABLE products RENAME COLUMN price_amount TO price;
@@ -0,0 +1,44 @@
+-- prices used to be whole units of the configured currency, they are now
+-- kept in its minor units together with the currency. The CURRENCY and
+-- MINOR_UNITS placeholders are filled in from the configuration.
+ALTER TABLE products RENAME COLUMN price TO price_amount;
+UPDATE products SET price_amount = COALESCE(price_amount, 0) * {{MINOR_UNITS}};
+ALTER TABLE products ADD COLUMN price_currency character varying(3) NOT NULL DEFAULT '{{CURRENCY}}';
+ALTER TABLE products ALTER COLUMN price_currency DROP DEFAULT;
+
+ALTER TABLE product_variants RENAME COLUMN price TO price_amount;
+UPDATE product_variants SET price_amount = price_amount * {{MINOR_UNITS}};
+ALTER TABLE product_variants ADD COLUMN price_currency character varying(3) NOT NULL DEFAULT '{{CURRENCY}}';
+ALTER TABLE product_variants ALTER COLUMN price_currency DROP DEFAULT;
+
+ALTER TABLE modifier_options RENAME COLUMN price_delta TO price_delta_amount;
+UPDATE modifier_options SET price_delta_amount = price_delta_amount * {{MINOR_UNITS}};
+ALTER TABLE modifier_options ADD COLUMN price_delta_currency character varying(3) NOT NULL DEFAULT '{{CURRENCY}}';
+ALTER TABLE modifier_options ALTER COLUMN price_delta_currency DROP DEFAULT;
+
+ALTER TABLE order_items RENAME COLUMN price TO price_amount;
+UPDATE order_items SET price_amount = COALESCE(price_amount, 0) * {{MINOR_UNITS}};
+ALTER TABLE order_items ADD COLUMN price_currency character varying(3) NOT NULL DEFAULT '{{CURRENCY}}';
+ALTER TABLE order_items ALTER COLUMN price_currency DROP DEFAULT;
+
+ALTER TABLE order_item_modifiers RENAME COLUMN price_delta TO price_delta_amount;
+UPDATE order_item_modifiers SET price_delta_amount = price_delta_amount * {{MINOR_UNITS}};
+ALTER TABLE order_item_modifiers ADD COLUMN price_delta_currency character varying(3) NOT NULL DEFAULT '{{CURRENCY}}';
+ALTER TABLE order_item_modifiers ALTER COLUMN price_delta_currency DROP DEFAULT;
+
+ALTER TABLE orders ADD COLUMN subtotal_amount bigint NOT NULL DEFAULT 0;
+ALTER TABLE orders ADD COLUMN subtotal_currency character varying(3) NOT NULL DEFAULT '';
+ALTER TABLE orders ADD COLUMN discount_amount bigint NOT NULL DEFAULT 0;
+ALTER TABLE orders ADD COLUMN discount_currency character varying(3) NOT NULL DEFAULT '';
+ALTER TABLE orders ADD COLUMN tax_amount bigint NOT NULL DEFAULT 0;
+ALTER TABLE orders ADD COLUMN tax_currency character varying(3) NOT NULL DEFAULT '';
+ALTER TABLE orders ADD COLUMN total_amount bigint NOT NULL DEFAULT 0;
+ALTER TABLE orders ADD COLUMN total_currency character varying(3) NOT NULL DEFAULT '';
+
+UPDATE orders SET subtotal_amount = (
+    SELECT COALESCE(SUM(price_amount * quantity), 0) FROM order_items
+    WHERE order_items.order_id = orders.id AND order_items.deleted_at IS NULL
+);
+UPDATE orders SET total_amount = subtotal_amount,
+    subtotal_currency = '{{CURRENCY}}', discount_currency = '{{CURRENCY}}', tax_currency = '{{CURRENCY}}', total_currency = '{{CURRENCY}}'
+WHERE EXISTS (SELECT 1 FROM order_items WHERE order_items.order_id = orders.id AND order_items.deleted_at IS NULL);
@@ -0,0 +1,5 @@
+ALTER TABLE order_items DROP COLUMN IF EXISTS product_name;
+
+ALTER TABLE orders DROP COLUMN IF EXISTS vat_included;
+ALTER TABLE orders DROP COLUMN IF EXISTS vat_rate;
+ALTER TABLE orders DROP COLUMN IF EXISTS discount_percent;
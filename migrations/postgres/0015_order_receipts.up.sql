@@ -0,0 +1,10 @@
+-- orders keep the discount and VAT their totals were computed with, and
+-- items the name of their product for receipts
+ALTER TABLE orders ADD COLUMN discount_percent integer NOT NULL DEFAULT 0;
+ALTER TABLE orders ADD COLUMN vat_rate integer NOT NULL DEFAULT 0;
+ALTER TABLE orders ADD COLUMN vat_included boolean NOT NULL DEFAULT true;
+
+ALTER TABLE order_items ADD COLUMN product_name text NOT NULL DEFAULT '';
+UPDATE order_items SET product_name = COALESCE(
+    (SELECT name FROM products WHERE products.id = order_items.product_id), ''
+);
@@ -0,0 +1,23 @@
+-- carts used to be pending orders, which stay as they are
+CREATE TABLE cart_items (
+    id bigserial PRIMARY KEY,
+    created_at timestamp with time zone,
+    updated_at timestamp with time zone,
+    deleted_at timestamp with time zone,
+    user_id bigint NOT NULL CONSTRAINT fk_users_cart_items REFERENCES users (id) ON DELETE CASCADE,
+    product_id bigint NOT NULL CONSTRAINT fk_cart_items_product REFERENCES products (id) ON DELETE CASCADE,
+    variant_id bigint CONSTRAINT fk_cart_items_variant REFERENCES product_variants (id) ON DELETE CASCADE,
+    quantity bigint NOT NULL
+);
+CREATE INDEX idx_cart_items_deleted_at ON cart_items (deleted_at);
+CREATE INDEX idx_cart_items_user_id ON cart_items (user_id);
+
+CREATE TABLE cart_item_modifiers (
+    id bigserial PRIMARY KEY,
+    cart_item_id bigint NOT NULL CONSTRAINT fk_cart_items_modifiers REFERENCES cart_items (id) ON DELETE CASCADE,
+    -- no foreign key: options replaced after an item was added stay
+    -- referenced, so the cart reports the item instead of dropping a paid
+    -- option
+    modifier_option_id bigint NOT NULL
+);
+CREATE INDEX idx_cart_item_modifiers_cart_item_id ON cart_item_modifiers (cart_item_id);
@@ -0,0 +1,47 @@
+CREATE TABLE IF NOT EXISTS users (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime,
+    name text,
+    email text CONSTRAINT uni_users_email UNIQUE,
+    password text,
+    role text NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users (deleted_at);
+
+CREATE TABLE IF NOT EXISTS products (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime,
+    name text CONSTRAINT uni_products_name UNIQUE,
+    quantity integer,
+    description text,
+    price integer
+);
+CREATE INDEX IF NOT EXISTS idx_products_deleted_at ON products (deleted_at);
+
+CREATE TABLE IF NOT EXISTS orders (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime,
+    user_id integer CONSTRAINT fk_orders_user REFERENCES users (id) ON UPDATE CASCADE ON DELETE SET NULL,
+    order_status text NOT NULL
+        CHECK (order_status IN ('pending', 'accepted', 'ready', 'out', 'delivered', 'confirmed', 'canceled'))
+);
+CREATE INDEX IF NOT EXISTS idx_orders_deleted_at ON orders (deleted_at);
+
+CREATE TABLE IF NOT EXISTS order_items (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime,
+    order_id integer CONSTRAINT fk_order_items_order REFERENCES orders (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    product_id integer CONSTRAINT fk_order_items_product REFERENCES products (id) ON UPDATE CASCADE ON DELETE SET NULL,
+    quantity integer,
+    price integer
+);
+CREATE INDEX IF NOT EXISTS idx_order_items_deleted_at ON order_items (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_order_items_order_id ON order_items (order_id);
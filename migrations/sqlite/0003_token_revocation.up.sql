@@ -0,0 +1,27 @@
+CREATE TABLE refresh_tokens (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime,
+    user_id integer NOT NULL REFERENCES users (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    token_hash text NOT NULL,
+    family_id text NOT NULL,
+    expires_at datetime NOT NULL,
+    revoked_at datetime
+);
+CREATE UNIQUE INDEX idx_refresh_tokens_token_hash ON refresh_tokens (token_hash);
+CREATE INDEX idx_refresh_tokens_user_id ON refresh_tokens (user_id);
+CREATE INDEX idx_refresh_tokens_family_id ON refresh_tokens (family_id);
+CREATE INDEX idx_refresh_tokens_deleted_at ON refresh_tokens (deleted_at);
+
+CREATE TABLE revoked_tokens (
+    jti text PRIMARY KEY,
+    expires_at datetime NOT NULL,
+    created_at datetime
+);
+CREATE INDEX idx_revoked_tokens_expires_at ON revoked_tokens (expires_at);
+
+CREATE TABLE user_token_cutoffs (
+    user_id integer PRIMARY KEY REFERENCES users (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    revoked_before datetime NOT NULL
+);
@@ -0,0 +1,6 @@
+CREATE TABLE login_throttles (
+    subject text PRIMARY KEY,
+    failures integer NOT NULL,
+    last_failure_at datetime,
+    locked_until datetime
+);
@@ -0,0 +1,3 @@
+ALTER TABLE refresh_tokens DROP COLUMN mfa;
+DROP TABLE IF EXISTS recovery_codes;
+DROP TABLE IF EXISTS user_mfa;
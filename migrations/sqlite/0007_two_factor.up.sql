@@ -0,0 +1,19 @@
+CREATE TABLE user_mfa (
+    user_id integer PRIMARY KEY REFERENCES users (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    totp_secret text NOT NULL,
+    enabled_at datetime,
+    last_step integer NOT NULL DEFAULT 0,
+    created_at datetime,
+    updated_at datetime
+);
+
+CREATE TABLE recovery_codes (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    user_id integer NOT NULL REFERENCES users (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    code_hash text NOT NULL,
+    used_at datetime,
+    created_at datetime
+);
+CREATE INDEX idx_recovery_codes_user_id ON recovery_codes (user_id);
+
+ALTER TABLE refresh_tokens ADD COLUMN mfa boolean NOT NULL DEFAULT false;
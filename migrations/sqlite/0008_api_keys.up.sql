@@ -0,0 +1,16 @@
+CREATE TABLE api_keys (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime,
+    name text NOT NULL,
+    prefix text NOT NULL,
+    key_hash text NOT NULL,
+    permissions text NOT NULL,
+    created_by integer,
+    expires_at datetime,
+    last_used_at datetime,
+    revoked_at datetime
+);
+CREATE UNIQUE INDEX idx_api_keys_key_hash ON api_keys (key_hash);
+CREATE INDEX idx_api_keys_deleted_at ON api_keys (deleted_at);
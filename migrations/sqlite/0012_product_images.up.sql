@@ -0,0 +1,9 @@
+CREATE TABLE product_images (
+    product_id integer PRIMARY KEY CONSTRAINT fk_products_image REFERENCES products (id) ON DELETE CASCADE,
+    key text NOT NULL,
+    content_type text NOT NULL,
+    width integer,
+    height integer,
+    widths text NOT NULL,
+    created_at datetime
+);
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS order_item_modifiers;
+ALTER TABLE order_items DROP COLUMN variant_name;
+ALTER TABLE order_items DROP COLUMN variant_id;
+DROP TABLE IF EXISTS modifier_options;
+DROP TABLE IF EXISTS modifier_groups;
+DROP TABLE IF EXISTS product_variants;
@@ -0,0 +1,29 @@
+-- amounts are rounded to whole units, fractions of a unit are lost
+ALTER TABLE orders DROP COLUMN total_currency;
+ALTER TABLE orders DROP COLUMN total_amount;
+ALTER TABLE orders DROP COLUMN tax_currency;
+ALTER TABLE orders DROP COLUMN tax_amount;
+ALTER TABLE orders DROP COLUMN discount_currency;
+ALTER TABLE orders DROP COLUMN discount_amount;
+ALTER TABLE orders DROP COLUMN subtotal_currency;
+ALTER TABLE orders DROP COLUMN subtotal_amount;
+
+ALTER TABLE order_item_modifiers DROP COLUMN price_delta_currency;
+UPDATE order_item_modifiers SET price_delta_amount = ROUND(price_delta_amount / {{MINOR_UNITS}}.0);
+ALTER TABLE order_item_modifiers RENAME COLUMN price_delta_amount TO price_delta;
+
+ALTER TABLE order_items DROP COLUMN price_currency;
+UPDATE order_items SET price_amount = ROUND(price_amount / {{MINOR_UNITS}}.0);
+ALTER TABLE order_items RENAME COLUMN price_amount TO price;
+
+ALTER TABLE modifier_options DROP COLUMN price_delta_currency;
+UPDATE modifier_options SET price_delta_amount = ROUND(price_delta_amount / {{MINOR_UNITS}}.0);
+ALTER TABLE modifier_options RENAME COLUMN price_delta_amount TO price_delta;
+
+ALTER TABLE product_variants DROP COLUMN price_currency;
+UPDATE product_variants SET price_amount = ROUND(price_amount / {{MINOR_UNITS}}.0);
+ALTER TABLE product_variants RENAME COLUMN price_amount TO price;
+
+ALTER TABLE products DROP COLUMN price_currency;
+UPDATE products SET price_amount = ROUND(price_amount / {{MINOR_UNITS}}.0);
+ALTER TABLE products RENAME COLUMN price_amount TO price;
@@ -0,0 +1,5 @@
+ALTER TABLE order_items DROP COLUMN product_name;
+
+ALTER TABLE orders DROP COLUMN vat_included;
+ALTER TABLE orders DROP COLUMN vat_rate;
+ALTER TABLE orders DROP COLUMN discount_percent;
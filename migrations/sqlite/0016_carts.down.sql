@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS cart_item_modifiers;
+DROP TABLE IF EXISTS cart_items;
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// APIKey lets an integration such as a kitchen display or a POS terminal
+// call the API without a user account. Only a hash of the key is stored.
+type APIKey struct {
+	gorm.Model
+	Name        string     `json:"name" gorm:"not null"`
+	Prefix      string     `json:"prefix" gorm:"not null"`
+	KeyHash     string     `json:"-" gorm:"not null;uniqueIndex"`
+	Permissions StringList `json:"permissions" gorm:"type:text;not null"`
+	CreatedBy   uint       `json:"created_by"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	LastUsedAt  *time.Time `json:"last_used_at"`
+	RevokedAt   *time.Time `json:"revoked_at"`
+}
+
+type APIKeyCreate struct {
+	Name        string     `json:"name" binding:"required"`
+	Permissions []string   `json:"permissions" binding:"required,min=1"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+}
+
+// StringList is stored as a space separated string, its items must not
+// contain spaces.
+type StringList []string
+
+func (l StringList) Value() (driver.Value, error) {
+	return strings.Join(l, " "), nil
+}
+
+func (l *StringList) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case string:
+		*l = strings.Fields(v)
+	case []byte:
+		*l = strings.Fields(string(v))
+	case nil:
+		*l = nil
+	default:
+		return fmt.Errorf("cannot scan %T into StringList", value)
+	}
+	return nil
+}
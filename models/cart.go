@@ -0,0 +1,72 @@
+package models
+
+import (
+	"go_final/money"
+
+	"gorm.io/gorm"
+)
+
+// CartItem is a product a customer means to order. It holds neither a price
+// nor stock, both are settled at checkout.
+type CartItem struct {
+	gorm.Model
+	UserID    uint               `gorm:"not null;index"`
+	ProductID uint               `gorm:"not null"`
+	VariantID *uint              // nil for products without variants
+	Modifiers []CartItemModifier `gorm:"foreignKey:CartItemID"`
+	Quantity  int
+}
+
+// CartItemModifier is a modifier option picked for a cart item.
+type CartItemModifier struct {
+	ID               uint `gorm:"primaryKey"`
+	CartItemID       uint `gorm:"not null;index"`
+	ModifierOptionID uint `gorm:"not null"`
+}
+
+// CartItemRequest picks a product, its variant and modifiers. Updates of a
+// cart item leave out the product.
+type CartItemRequest struct {
+	ProductID   uint   `json:"product_id"`
+	VariantID   uint   `json:"variant_id,omitempty"`
+	ModifierIDs []uint `json:"modifier_ids,omitempty"`
+	Quantity    int    `json:"quantity"`
+}
+
+// Request is the item as it would be ordered.
+func (item CartItem) Request() CartItemRequest {
+	request := CartItemRequest{ProductID: item.ProductID, Quantity: item.Quantity}
+	if item.VariantID != nil {
+		request.VariantID = *item.VariantID
+	}
+	for _, modifier := range item.Modifiers {
+		request.ModifierIDs = append(request.ModifierIDs, modifier.ModifierOptionID)
+	}
+	return request
+}
+
+// Cart is the cart of a customer priced at the current prices.
+type Cart struct {
+	Items []CartLine `json:"items"`
+	// Subtotal adds up the items that can be ordered, discounts and VAT
+	// are applied at checkout.
+	Subtotal money.Money `json:"subtotal"`
+}
+
+type CartLine struct {
+	ID          uint                `json:"id"`
+	ProductID   uint                `json:"product_id"`
+	ProductName string              `json:"product_name"`
+	VariantID   *uint               `json:"variant_id,omitempty"`
+	Variant     string              `json:"variant,omitempty"`
+	Modifiers   []OrderItemModifier `json:"modifiers"`
+	Quantity    int                 `json:"quantity"`
+	UnitPrice   money.Money         `json:"unit_price"`
+	Total       money.Money         `json:"total"`
+	// InStock tells whether there is enough stock for the item right now,
+	// it is only reserved at checkout.
+	InStock bool `json:"in_stock"`
+	// Error says why the item cannot be ordered any more, e.g. because a
+	// modifier it picked was removed.
+	Error string `json:"error,omitempty"`
+}
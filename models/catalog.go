@@ -0,0 +1,25 @@
+package models
+
+import "gorm.io/gorm"
+
+// Category is a section of the menu such as "Soups" or "Drinks". Sections
+// are listed by Position, then by name.
+type Category struct {
+	gorm.Model
+	Name        string `json:"name" binding:"required" gorm:"unique"`
+	Description string `json:"description"`
+	Position    int    `json:"position"`
+}
+
+// Tag marks products across categories, e.g. "vegan" or "halal". Names are
+// stored in lower case.
+type Tag struct {
+	gorm.Model
+	Name string `json:"name" binding:"required,max=32" gorm:"unique"`
+}
+
+// MenuSection is a category together with its products.
+type MenuSection struct {
+	Category
+	Products []Product `json:"products"`
+}
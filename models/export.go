@@ -0,0 +1,32 @@
+package models
+
+import (
+	"go_final/money"
+	"time"
+)
+
+// UserExport is the data of a user handed out on a data portability
+// request: the profile and the full order history.
+type UserExport struct {
+	ExportedAt time.Time     `json:"exported_at"`
+	User       APIUser       `json:"user"`
+	Orders     []OrderExport `json:"orders"`
+}
+
+type OrderExport struct {
+	ID        uint              `json:"id"`
+	Status    OrderStatus       `json:"status"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+	Total     money.Money       `json:"total"`
+	Items     []OrderItemExport `json:"items"`
+}
+
+type OrderItemExport struct {
+	ProductID   uint        `json:"product_id"`
+	ProductName string      `json:"product_name"`
+	Variant     string      `json:"variant,omitempty"`
+	Modifiers   []string    `json:"modifiers,omitempty"`
+	Quantity    int         `json:"quantity"`
+	Price       money.Money `json:"price"`
+}
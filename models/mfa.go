@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+// UserMFA holds the TOTP secret of a user. EnabledAt stays nil from
+// enrollment until the user confirms a first code.
+type UserMFA struct {
+	UserID     uint   `gorm:"primaryKey;autoIncrement:false"`
+	TOTPSecret string `gorm:"column:totp_secret;not null"`
+	EnabledAt  *time.Time
+	// LastStep is the TOTP time step of the last accepted code, codes of
+	// that step or earlier are rejected to prevent replays.
+	LastStep  int64 `gorm:"not null;default:0"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (UserMFA) TableName() string {
+	return "user_mfa"
+}
+
+// RecoveryCode is a single-use code that replaces a TOTP code when the
+// authenticator is lost, stored hashed.
+type RecoveryCode struct {
+	ID        uint   `gorm:"primaryKey"`
+	UserID    uint   `gorm:"not null;index"`
+	CodeHash  string `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+type MFACodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// MFAVerifyRequest carries either a TOTP code or a recovery code.
+type MFAVerifyRequest struct {
+	Code         string `json:"code,omitempty"`
+	RecoveryCode string `json:"recovery_code,omitempty"`
+}
+
+type MFASignInRequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	MFAVerifyRequest
+}
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"go_final/money"
+
 	"gorm.io/gorm"
 )
 
@@ -9,6 +11,17 @@ type Order struct {
 	User        User `gorm:"foreignkey:UserID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
 	UserID      uint
 	OrderStatus OrderStatus `gorm:"type:varchar(100);not null"`
+	// The totals are computed from the items whenever they change: Total is
+	// Subtotal less Discount, plus Tax unless VATIncluded.
+	Subtotal money.Money `gorm:"embedded;embeddedPrefix:subtotal_"`
+	Discount money.Money `gorm:"embedded;embeddedPrefix:discount_"`
+	Tax      money.Money `gorm:"embedded;embeddedPrefix:tax_"`
+	Total    money.Money `gorm:"embedded;embeddedPrefix:total_"`
+	// DiscountPercent is granted by the cashier. VATRate, in hundredths of a
+	// percent, and VATIncluded are those the totals were computed with.
+	DiscountPercent int
+	VATRate         int
+	VATIncluded     bool
 }
 
 type OrderStatus string
@@ -29,19 +42,20 @@ type OrderItems struct {
 	Product   Product `gorm:"foreignkey:ProductID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
 	OrderID   uint
 	ProductID uint
-	Quantity  int
-	Price     int
-}
-
-type CartItemRequest struct {
-	Product     Product `gorm:"foreignkey:ProductID"`
-	OrderItemID uint    `json:"order_item_id,omitempty"`
-	ProductID   uint    `json:"product_id"`
-	Quantity    int     `json:"quantity"`
+	// ProductName and VariantName are copied from the product and the
+	// variant, which may be deleted later.
+	ProductName string
+	VariantID   *uint
+	VariantName string
+	Modifiers   []OrderItemModifier `gorm:"foreignKey:OrderItemID"`
+	Quantity    int
+	// Price is the unit price: the price of the variant, or of the product
+	// without variants, plus the deltas of the modifiers.
+	Price money.Money `gorm:"embedded;embeddedPrefix:price_"`
 }
 
-type CartRequest struct {
-	CartItems []CartItemRequest `json:"order"`
+type DiscountRequest struct {
+	Percent *int `json:"percent" binding:"required,min=0,max=100"`
 }
 
 // TODO: switch mechanism between order statuses
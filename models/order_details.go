@@ -0,0 +1,78 @@
+package models
+
+import (
+	"go_final/money"
+	"time"
+)
+
+// OrderDetails is the full view of an order shown to the customer and the
+// cashier, and printed on its receipt.
+type OrderDetails struct {
+	ID        uint        `json:"id"`
+	Status    OrderStatus `json:"status"`
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
+	// User is nil once the customer deleted their account.
+	User            *APIUser    `json:"user"`
+	Items           []OrderLine `json:"items"`
+	Subtotal        money.Money `json:"subtotal"`
+	DiscountPercent int         `json:"discount_percent"`
+	Discount        money.Money `json:"discount"`
+	// VATRate is a percentage.
+	VATRate     float64     `json:"vat_rate"`
+	VATIncluded bool        `json:"vat_included"`
+	Tax         money.Money `json:"tax"`
+	Total       money.Money `json:"total"`
+}
+
+// OrderLine is an item of an order at the prices it was ordered at.
+type OrderLine struct {
+	ID          uint                `json:"id"`
+	ProductID   uint                `json:"product_id"`
+	ProductName string              `json:"product_name"`
+	Variant     string              `json:"variant,omitempty"`
+	Modifiers   []OrderItemModifier `json:"modifiers"`
+	Quantity    int                 `json:"quantity"`
+	UnitPrice   money.Money         `json:"unit_price"`
+	Total       money.Money         `json:"total"`
+}
+
+// NewOrderDetails puts an order together with its items and customer.
+func NewOrderDetails(order Order, items []OrderItems, user *APIUser) (OrderDetails, error) {
+	details := OrderDetails{
+		ID:              order.ID,
+		Status:          order.OrderStatus,
+		CreatedAt:       order.CreatedAt,
+		UpdatedAt:       order.UpdatedAt,
+		User:            user,
+		Items:           make([]OrderLine, 0, len(items)),
+		Subtotal:        order.Subtotal,
+		DiscountPercent: order.DiscountPercent,
+		Discount:        order.Discount,
+		VATRate:         float64(order.VATRate) / 100,
+		VATIncluded:     order.VATIncluded,
+		Tax:             order.Tax,
+		Total:           order.Total,
+	}
+	for _, item := range items {
+		total, err := item.Price.Mul(int64(item.Quantity))
+		if err != nil {
+			return OrderDetails{}, err
+		}
+		modifiers := item.Modifiers
+		if modifiers == nil {
+			modifiers = []OrderItemModifier{}
+		}
+		details.Items = append(details.Items, OrderLine{
+			ID:          item.ID,
+			ProductID:   item.ProductID,
+			ProductName: item.ProductName,
+			Variant:     item.VariantName,
+			Modifiers:   modifiers,
+			Quantity:    item.Quantity,
+			UnitPrice:   item.Price,
+			Total:       total,
+		})
+	}
+	return details, nil
+}
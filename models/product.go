@@ -1,11 +1,24 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"go_final/money"
+
+	"gorm.io/gorm"
+)
 
 type Product struct {
 	gorm.Model
-	Name        string `json:"name" gorm:"unique"`
-	Quantity    int    `json:"quantity"`
-	Description string `json:"description"`
-	Price       int    `json:"price"`
+	Name           string           `json:"name" form:"name" gorm:"unique"`
+	Quantity       int              `json:"quantity" form:"quantity"`
+	Description    string           `json:"description" form:"description"`
+	Price          money.Money      `json:"price" form:"price" gorm:"embedded;embeddedPrefix:price_"`
+	Image          *ProductImage    `json:"image"`
+	Variants       []ProductVariant `json:"variants"`
+	ModifierGroups []ModifierGroup  `json:"modifier_groups"`
+	Categories     []Category       `json:"categories" gorm:"many2many:product_categories"`
+	Tags           []Tag            `json:"tags" gorm:"many2many:product_tags"`
+	// CategoryIDs and TagIDs replace the categories and tags of the product
+	// when it is created or updated, they are left alone when omitted.
+	CategoryIDs []uint `json:"category_ids,omitempty" form:"category_ids" gorm:"-"`
+	TagIDs      []uint `json:"tag_ids,omitempty" form:"tag_ids" gorm:"-"`
 }
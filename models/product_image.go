@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// ProductImage is the picture of a product. Its files are kept in the blob
+// store under Key: the upload itself and a thumbnail for each of Widths.
+type ProductImage struct {
+	ProductID   uint       `json:"-" gorm:"primaryKey;autoIncrement:false"`
+	Key         string     `json:"-" gorm:"not null"`
+	ContentType string     `json:"content_type" gorm:"not null"`
+	Width       int        `json:"width"`
+	Height      int        `json:"height"`
+	Widths      StringList `json:"-" gorm:"type:text;not null"`
+	CreatedAt   time.Time  `json:"created_at"`
+	// URL and Thumbnails, keyed by width, are filled in from the blob store
+	// for responses.
+	URL        string            `json:"url" gorm:"-"`
+	Thumbnails map[string]string `json:"thumbnails" gorm:"-"`
+}
@@ -0,0 +1,90 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RefreshToken is stored hashed, the plain token is only ever sent to the
+// client. Tokens rotated from the same sign-in share a FamilyID so that
+// reuse of an old token can revoke the whole chain.
+type RefreshToken struct {
+	gorm.Model
+	UserID    uint   `gorm:"not null;index"`
+	TokenHash string `gorm:"not null;uniqueIndex"`
+	FamilyID  string `gorm:"not null;index"`
+	// MFA records that the sign-in used a second factor, so that refreshed
+	// access tokens keep saying so.
+	MFA       bool `gorm:"not null;default:false"`
+	ExpiresAt time.Time
+	RevokedAt *time.Time
+}
+
+// RevokedToken is an access token revoked before its expiry, kept until the
+// token would have expired anyway.
+type RevokedToken struct {
+	JTI       string `gorm:"primaryKey"`
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
+
+// UserTokenCutoff invalidates every access token of a user issued before
+// RevokedBefore, e.g. after a password change.
+type UserTokenCutoff struct {
+	UserID        uint `gorm:"primaryKey;autoIncrement:false"`
+	RevokedBefore time.Time
+}
+
+type UserTokenPurpose string
+
+const (
+	VerifyEmailPurpose   UserTokenPurpose = "verify_email"
+	ResetPasswordPurpose UserTokenPurpose = "reset_password"
+	ChangeEmailPurpose   UserTokenPurpose = "change_email"
+)
+
+// UserToken is a single-use token mailed to a user to prove they control
+// the address, stored hashed like refresh tokens.
+type UserToken struct {
+	gorm.Model
+	UserID    uint             `gorm:"not null;index"`
+	Purpose   UserTokenPurpose `gorm:"type:varchar(32);not null"`
+	TokenHash string           `gorm:"not null;uniqueIndex"`
+	// Email is the address the token was sent to, for change_email tokens
+	// the new address of the user.
+	Email     string `gorm:"type:varchar(255)"`
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+}
+
+// LoginThrottle counts failed sign-ins for one subject, an email address
+// ("email:<address>") or a client IP ("ip:<address>").
+type LoginThrottle struct {
+	Subject       string `gorm:"primaryKey"`
+	Failures      int    `gorm:"not null"`
+	LastFailureAt time.Time
+	LockedUntil   *time.Time
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token,omitempty"`
+	All          bool   `json:"all,omitempty"`
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type EmailRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
@@ -1,6 +1,10 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type User struct {
 	gorm.Model
@@ -8,13 +12,23 @@ type User struct {
 	Email    string   `json:"email" binding:"required,email" gorm:"unique"`
 	Password string   `json:"password" binding:"required"`
 	Role     UserRole `gorm:"type:varchar(100);not null"`
+	// EmailVerifiedAt is nil until the user follows the verification link,
+	// unverified users cannot sign in.
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	// AnonymizedAt is set once the personal data of the user was scrubbed.
+	// The row is kept, deleted, so that their orders still add up.
+	AnonymizedAt *time.Time `json:"anonymized_at"`
 }
 
 type APIUser struct {
-	ID    uint
-	Name  string
-	Email string
-	Role  UserRole
+	ID              uint
+	Name            string
+	Email           string
+	Role            UserRole
+	EmailVerifiedAt *time.Time
+	CreatedAt       time.Time
+	DeletedAt       *time.Time `json:",omitempty"`
+	AnonymizedAt    *time.Time `json:",omitempty"`
 }
 
 type UserRegister struct {
@@ -34,9 +48,29 @@ type UserUpdate struct {
 	Password string `json:"password,omitempty"`
 }
 
+// ProfileUpdate is a change of the caller's own account. Changing the email
+// or the password needs the current password.
+type ProfileUpdate struct {
+	Name            string `json:"name,omitempty"`
+	Email           string `json:"email,omitempty" binding:"omitempty,email"`
+	Password        string `json:"password,omitempty"`
+	CurrentPassword string `json:"current_password,omitempty"`
+}
+
+type PasswordConfirmation struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+}
+
+type UserRoleUpdate struct {
+	Role UserRole `json:"role" binding:"required"`
+}
+
 type UserRole string
 
 const (
 	ADMIN_ROLE    UserRole = "admin"
 	CUSTOMER_ROLE UserRole = "customer"
+	CASHIER_ROLE  UserRole = "cashier"
+	COOK_ROLE     UserRole = "cook"
+	COURIER_ROLE  UserRole = "courier"
 )
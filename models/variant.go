@@ -0,0 +1,53 @@
+package models
+
+import (
+	"go_final/money"
+
+	"gorm.io/gorm"
+)
+
+// ProductVariant is a version of a product with its own price and stock,
+// such as a size. Products with variants are ordered by variant and their
+// own price and quantity are not used.
+type ProductVariant struct {
+	gorm.Model
+	ProductID uint        `json:"product_id" gorm:"not null;index"`
+	Name      string      `json:"name" binding:"required"`
+	Price     money.Money `json:"price" gorm:"embedded;embeddedPrefix:price_"`
+	Quantity  int         `json:"quantity" binding:"min=0"`
+	Position  int         `json:"position"`
+}
+
+// ModifierGroup offers add-ons or choices for a product. An order item picks
+// between MinSelections and MaxSelections of its options, a group with
+// MinSelections above zero is required.
+type ModifierGroup struct {
+	gorm.Model
+	ProductID     uint             `json:"product_id" gorm:"not null;index"`
+	Name          string           `json:"name" binding:"required"`
+	MinSelections int              `json:"min_selections" binding:"min=0"`
+	MaxSelections int              `json:"max_selections" binding:"min=1,gtefield=MinSelections"`
+	Position      int              `json:"position"`
+	Options       []ModifierOption `json:"options" binding:"required,min=1,dive" gorm:"foreignKey:GroupID"`
+}
+
+// ModifierOption adds PriceDelta, which may be negative, to the price of an
+// order item.
+type ModifierOption struct {
+	gorm.Model
+	GroupID    uint        `json:"group_id" gorm:"not null;index"`
+	Name       string      `json:"name" binding:"required"`
+	PriceDelta money.Money `json:"price_delta" gorm:"embedded;embeddedPrefix:price_delta_"`
+	Position   int         `json:"position"`
+}
+
+// OrderItemModifier records an option picked for an order item. Name and
+// PriceDelta are copied so that the order keeps its price when the option
+// changes or is deleted.
+type OrderItemModifier struct {
+	ID               uint        `json:"id" gorm:"primaryKey"`
+	OrderItemID      uint        `json:"order_item_id" gorm:"not null;index"`
+	ModifierOptionID *uint       `json:"modifier_option_id"`
+	Name             string      `json:"name"`
+	PriceDelta       money.Money `json:"price_delta" gorm:"embedded;embeddedPrefix:price_delta_"`
+}
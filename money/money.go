@@ -0,0 +1,243 @@
+// Package money represents amounts of money in the minor units of their
+// currency, such as cents or tiyn, so that sums never pick up rounding
+// errors.
+package money
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrCurrencyMismatch = errors.New("money: amounts are in different currencies")
+	ErrOverflow         = errors.New("money: amount out of range")
+	ErrUnknownCurrency  = errors.New("money: unknown currency")
+)
+
+// minorUnits is the number of decimal places of the minor unit of each
+// supported ISO 4217 currency.
+var minorUnits = map[string]int{
+	"KZT": 2, "RUB": 2, "KGS": 2, "UZS": 2, "USD": 2, "EUR": 2, "GBP": 2,
+	"CNY": 2, "TRY": 2, "AED": 2, "JPY": 0, "KRW": 0, "KWD": 3, "BHD": 3,
+}
+
+// Money is an amount in the minor units of Currency, an ISO 4217 code. The
+// zero Money has no currency and takes the currency of whatever it is added
+// to, so it can start a sum.
+type Money struct {
+	Amount   int64  `json:"amount" gorm:"column:amount"`
+	Currency string `json:"currency" gorm:"column:currency;size:3"`
+}
+
+func New(amount int64, currency string) Money {
+	return Money{Amount: amount, Currency: currency}
+}
+
+// ValidCurrency reports whether code is a supported currency.
+func ValidCurrency(code string) bool {
+	_, ok := minorUnits[code]
+	return ok
+}
+
+// Exponent is the number of decimal places of the minor unit of currency.
+func Exponent(currency string) (int, error) {
+	exp, ok := minorUnits[currency]
+	if !ok {
+		return 0, fmt.Errorf("%w %q", ErrUnknownCurrency, currency)
+	}
+	return exp, nil
+}
+
+func (m Money) IsZero() bool     { return m.Amount == 0 }
+func (m Money) IsNegative() bool { return m.Amount < 0 }
+
+// unify returns the common currency of m and o.
+func (m Money) unify(o Money) (string, error) {
+	switch {
+	case m.Currency == o.Currency:
+		return m.Currency, nil
+	case m == Money{}:
+		return o.Currency, nil
+	case o == Money{}:
+		return m.Currency, nil
+	}
+	return "", fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.Currency, o.Currency)
+}
+
+func (m Money) Add(o Money) (Money, error) {
+	currency, err := m.unify(o)
+	if err != nil {
+		return Money{}, err
+	}
+	sum := m.Amount + o.Amount
+	if (o.Amount > 0 && sum < m.Amount) || (o.Amount < 0 && sum > m.Amount) {
+		return Money{}, ErrOverflow
+	}
+	return Money{Amount: sum, Currency: currency}, nil
+}
+
+func (m Money) Sub(o Money) (Money, error) {
+	currency, err := m.unify(o)
+	if err != nil {
+		return Money{}, err
+	}
+	diff := m.Amount - o.Amount
+	if (o.Amount > 0 && diff > m.Amount) || (o.Amount < 0 && diff < m.Amount) {
+		return Money{}, ErrOverflow
+	}
+	return Money{Amount: diff, Currency: currency}, nil
+}
+
+// Mul multiplies m by a count, such as the quantity of an order item.
+func (m Money) Mul(n int64) (Money, error) {
+	product := m.Amount * n
+	if m.Amount != 0 && (product/m.Amount != n || (m.Amount == -1 && n == math.MinInt64)) {
+		return Money{}, ErrOverflow
+	}
+	return Money{Amount: product, Currency: m.Currency}, nil
+}
+
+// Cmp returns -1, 0 or +1 as m is less than, equal to or greater than o.
+func (m Money) Cmp(o Money) (int, error) {
+	if _, err := m.unify(o); err != nil {
+		return 0, err
+	}
+	switch {
+	case m.Amount < o.Amount:
+		return -1, nil
+	case m.Amount > o.Amount:
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// Rounding decides what happens to fractions of a minor unit.
+type Rounding int
+
+const (
+	// HalfUp rounds to the nearest minor unit and halves away from zero,
+	// the usual rule for prices and taxes.
+	HalfUp Rounding = iota
+	// HalfEven rounds halves to the even neighbour, banker's rounding.
+	HalfEven
+	// Down truncates towards zero.
+	Down
+	// Up rounds away from zero.
+	Up
+)
+
+// MulRatio returns m * num / den rounded to a whole minor unit, e.g.
+// m.MulRatio(12, 100, money.HalfUp) is 12% of m.
+func (m Money) MulRatio(num, den int64, rounding Rounding) (Money, error) {
+	if den == 0 {
+		return Money{}, errors.New("money: division by zero")
+	}
+	n := new(big.Int).Mul(big.NewInt(m.Amount), big.NewInt(num))
+	d := big.NewInt(den)
+	if d.Sign() < 0 {
+		n.Neg(n)
+		d.Neg(d)
+	}
+	q, r := new(big.Int).QuoRem(n, d, new(big.Int))
+	if r.Sign() != 0 {
+		// twice the remainder against the divisor tells below, at or
+		// above half
+		half := new(big.Int).Abs(r)
+		half.Lsh(half, 1)
+		cmp := half.Cmp(d)
+		away := false
+		switch rounding {
+		case HalfUp:
+			away = cmp >= 0
+		case HalfEven:
+			away = cmp > 0 || (cmp == 0 && q.Bit(0) == 1)
+		case Up:
+			away = true
+		}
+		if away {
+			q.Add(q, big.NewInt(int64(r.Sign())))
+		}
+	}
+	if !q.IsInt64() {
+		return Money{}, ErrOverflow
+	}
+	return Money{Amount: q.Int64(), Currency: m.Currency}, nil
+}
+
+// Sum adds up amounts, all in the same currency.
+func Sum(amounts ...Money) (Money, error) {
+	var total Money
+	for _, amount := range amounts {
+		var err error
+		if total, err = total.Add(amount); err != nil {
+			return Money{}, err
+		}
+	}
+	return total, nil
+}
+
+// String formats m with the decimal places of its currency, e.g.
+// "1250.00 KZT".
+func (m Money) String() string {
+	exp, err := Exponent(m.Currency)
+	if err != nil || exp == 0 {
+		return strings.TrimSpace(strconv.FormatInt(m.Amount, 10) + " " + m.Currency)
+	}
+	sign, amount := "", m.Amount
+	if amount < 0 {
+		sign = "-"
+	}
+	digits := strconv.FormatUint(absUint(amount), 10)
+	if len(digits) <= exp {
+		digits = strings.Repeat("0", exp-len(digits)+1) + digits
+	}
+	point := len(digits) - exp
+	return sign + digits[:point] + "." + digits[point:] + " " + m.Currency
+}
+
+func absUint(n int64) uint64 {
+	if n < 0 {
+		return uint64(-(n + 1)) + 1
+	}
+	return uint64(n)
+}
+
+// UnmarshalJSON takes {"amount": 1250, "currency": "KZT"}, or a bare number
+// of minor units which leaves the currency for the caller to fill in.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+	if len(data) > 0 && data[0] != '{' {
+		amount, err := strconv.ParseInt(string(data), 10, 64)
+		if err != nil {
+			return fmt.Errorf("money: amount must be a whole number of minor units: %s", data)
+		}
+		*m = Money{Amount: amount}
+		return nil
+	}
+
+	var fields struct {
+		Amount   *int64 `json:"amount"`
+		Currency string `json:"currency"`
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	if fields.Amount == nil {
+		return errors.New("money: amount is missing")
+	}
+	currency := strings.ToUpper(fields.Currency)
+	if currency != "" && !ValidCurrency(currency) {
+		return fmt.Errorf("%w %q", ErrUnknownCurrency, fields.Currency)
+	}
+	*m = Money{Amount: *fields.Amount, Currency: currency}
+	return nil
+}
@@ -0,0 +1,244 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestAdd(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b Money
+		want Money
+		err  error
+	}{
+		{"same currency", New(150, "KZT"), New(250, "KZT"), New(400, "KZT"), nil},
+		{"negative", New(150, "KZT"), New(-250, "KZT"), New(-100, "KZT"), nil},
+		{"zero takes currency", Money{}, New(5, "USD"), New(5, "USD"), nil},
+		{"takes zero", New(5, "USD"), Money{}, New(5, "USD"), nil},
+		{"zero amount keeps currency", New(0, "KZT"), New(5, "USD"), Money{}, ErrCurrencyMismatch},
+		{"mismatch", New(1, "KZT"), New(1, "USD"), Money{}, ErrCurrencyMismatch},
+		{"max", New(math.MaxInt64-1, "KZT"), New(1, "KZT"), New(math.MaxInt64, "KZT"), nil},
+		{"overflow", New(math.MaxInt64, "KZT"), New(1, "KZT"), Money{}, ErrOverflow},
+		{"min", New(math.MinInt64+1, "KZT"), New(-1, "KZT"), New(math.MinInt64, "KZT"), nil},
+		{"underflow", New(math.MinInt64, "KZT"), New(-1, "KZT"), Money{}, ErrOverflow},
+		{"min plus max", New(math.MinInt64, "KZT"), New(math.MaxInt64, "KZT"), New(-1, "KZT"), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.a.Add(tt.b)
+			if !errors.Is(err, tt.err) || got != tt.want {
+				t.Errorf("%v.Add(%v) = %v, %v, want %v, %v", tt.a, tt.b, got, err, tt.want, tt.err)
+			}
+		})
+	}
+}
+
+func TestSub(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b Money
+		want Money
+		err  error
+	}{
+		{"same currency", New(400, "KZT"), New(150, "KZT"), New(250, "KZT"), nil},
+		{"below zero", New(150, "KZT"), New(400, "KZT"), New(-250, "KZT"), nil},
+		{"from zero", Money{}, New(5, "USD"), New(-5, "USD"), nil},
+		{"mismatch", New(1, "KZT"), New(1, "USD"), Money{}, ErrCurrencyMismatch},
+		{"overflow", New(math.MaxInt64, "KZT"), New(-1, "KZT"), Money{}, ErrOverflow},
+		{"underflow", New(math.MinInt64, "KZT"), New(1, "KZT"), Money{}, ErrOverflow},
+		{"min from minus one", New(-1, "KZT"), New(math.MinInt64, "KZT"), New(math.MaxInt64, "KZT"), nil},
+		{"min from zero", New(0, "KZT"), New(math.MinInt64, "KZT"), Money{}, ErrOverflow},
+		{"min from min", New(math.MinInt64, "KZT"), New(math.MinInt64, "KZT"), New(0, "KZT"), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.a.Sub(tt.b)
+			if !errors.Is(err, tt.err) || got != tt.want {
+				t.Errorf("%v.Sub(%v) = %v, %v, want %v, %v", tt.a, tt.b, got, err, tt.want, tt.err)
+			}
+		})
+	}
+}
+
+func TestMul(t *testing.T) {
+	tests := []struct {
+		name string
+		m    Money
+		n    int64
+		want Money
+		err  error
+	}{
+		{"quantity", New(1505, "KZT"), 3, New(4515, "KZT"), nil},
+		{"zero", New(1505, "KZT"), 0, New(0, "KZT"), nil},
+		{"zero amount", New(0, "KZT"), math.MaxInt64, New(0, "KZT"), nil},
+		{"negative", New(-5, "KZT"), 3, New(-15, "KZT"), nil},
+		{"overflow", New(math.MaxInt64/2+1, "KZT"), 2, Money{}, ErrOverflow},
+		{"negative overflow", New(math.MinInt64/2-1, "KZT"), 2, Money{}, ErrOverflow},
+		{"min times one", New(math.MinInt64, "KZT"), 1, New(math.MinInt64, "KZT"), nil},
+		{"min times minus one", New(math.MinInt64, "KZT"), -1, Money{}, ErrOverflow},
+		{"minus one times min", New(-1, "KZT"), math.MinInt64, Money{}, ErrOverflow},
+		{"one times min", New(1, "KZT"), math.MinInt64, New(math.MinInt64, "KZT"), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.m.Mul(tt.n)
+			if !errors.Is(err, tt.err) || got != tt.want {
+				t.Errorf("%v.Mul(%d) = %v, %v, want %v, %v", tt.m, tt.n, got, err, tt.want, tt.err)
+			}
+		})
+	}
+}
+
+func TestMulRatio(t *testing.T) {
+	tests := []struct {
+		name     string
+		amount   int64
+		num, den int64
+		rounding Rounding
+		want     int64
+	}{
+		{"exact", 1000, 12, 100, HalfUp, 120},
+		{"vat", 4515, 12, 100, HalfUp, 542},
+
+		{"half up below half", 14, 1, 10, HalfUp, 1},
+		{"half up at half", 15, 1, 10, HalfUp, 2},
+		{"half up negative at half", -15, 1, 10, HalfUp, -2},
+		{"half up negative below half", -14, 1, 10, HalfUp, -1},
+		{"half up negative denominator", 15, 1, -10, HalfUp, -2},
+		{"half up both negative", -15, -1, -10, HalfUp, -2},
+
+		{"half even at half to even", 25, 1, 10, HalfEven, 2},
+		{"half even at half from odd", 35, 1, 10, HalfEven, 4},
+		{"half even above half", 26, 1, 10, HalfEven, 3},
+		{"half even negative to even", -25, 1, 10, HalfEven, -2},
+		{"half even negative from odd", -35, 1, 10, HalfEven, -4},
+		{"half even negative denominator", 35, 1, -10, HalfEven, -4},
+
+		{"down", 19, 1, 10, Down, 1},
+		{"down negative", -19, 1, 10, Down, -1},
+		{"down negative denominator", 19, 1, -10, Down, -1},
+
+		{"up", 11, 1, 10, Up, 2},
+		{"up exact", 10, 1, 10, Up, 1},
+		{"up negative", -11, 1, 10, Up, -2},
+		{"up negative denominator", 11, 1, -10, Up, -2},
+
+		{"min amount", math.MinInt64, 1, 1, HalfUp, math.MinInt64},
+		{"min amount halved", math.MinInt64, 1, 2, HalfUp, math.MinInt64 / 2},
+		{"intermediate beyond int64", math.MaxInt64, 100, 100, HalfUp, math.MaxInt64},
+		{"max ratio of min", math.MinInt64, math.MaxInt64, math.MaxInt64, Down, math.MinInt64},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := New(tt.amount, "KZT").MulRatio(tt.num, tt.den, tt.rounding)
+			if err != nil || got != New(tt.want, "KZT") {
+				t.Errorf("MulRatio(%d, %d/%d) = %v, %v, want %d", tt.amount, tt.num, tt.den, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestMulRatioErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		amount   int64
+		num, den int64
+		err      error
+	}{
+		{"overflow", math.MaxInt64, 2, 1, ErrOverflow},
+		{"min negated", math.MinInt64, -1, 1, ErrOverflow},
+		{"min by negative denominator", math.MinInt64, 1, -1, ErrOverflow},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(tt.amount, "KZT").MulRatio(tt.num, tt.den, HalfUp); !errors.Is(err, tt.err) {
+				t.Errorf("MulRatio() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+	if _, err := New(1, "KZT").MulRatio(1, 0, HalfUp); err == nil {
+		t.Error("MulRatio() by zero succeeded")
+	}
+}
+
+func TestString(t *testing.T) {
+	tests := []struct {
+		m    Money
+		want string
+	}{
+		{New(125000, "KZT"), "1250.00 KZT"},
+		{New(5, "USD"), "0.05 USD"},
+		{New(0, "USD"), "0.00 USD"},
+		{New(-5, "USD"), "-0.05 USD"},
+		{New(-125050, "KZT"), "-1250.50 KZT"},
+		{New(1250, "JPY"), "1250 JPY"},
+		{New(-1250, "JPY"), "-1250 JPY"},
+		{New(1234, "KWD"), "1.234 KWD"},
+		{New(5, "KWD"), "0.005 KWD"},
+		{New(-50, "BHD"), "-0.050 BHD"},
+		{New(math.MaxInt64, "USD"), "92233720368547758.07 USD"},
+		{New(math.MinInt64, "USD"), "-92233720368547758.08 USD"},
+		{New(math.MinInt64, "KWD"), "-9223372036854775.808 KWD"},
+		{New(12, "XXX"), "12 XXX"},
+		{New(12, ""), "12"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := tt.m.String(); got != tt.want {
+				t.Errorf("String() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    Money
+		wantErr bool
+	}{
+		{"object", `{"amount": 1250, "currency": "KZT"}`, New(1250, "KZT"), false},
+		{"lower case currency", `{"amount": 1250, "currency": "usd"}`, New(1250, "USD"), false},
+		{"object without currency", `{"amount": -5}`, New(-5, ""), false},
+		{"bare number", `1250`, New(1250, ""), false},
+		{"padded bare number", " 1250 ", New(1250, ""), false},
+		{"negative bare number", `-5`, New(-5, ""), false},
+		{"max", `9223372036854775807`, New(math.MaxInt64, ""), false},
+		{"min", `-9223372036854775808`, New(math.MinInt64, ""), false},
+		{"null", `null`, Money{}, false},
+		{"too large", `9223372036854775808`, Money{}, true},
+		{"fraction", `12.50`, Money{}, true},
+		{"string", `"12"`, Money{}, true},
+		{"unknown currency", `{"amount": 1, "currency": "XXX"}`, Money{}, true},
+		{"missing amount", `{"currency": "KZT"}`, Money{}, true},
+		{"fractional amount", `{"amount": 1.5, "currency": "KZT"}`, Money{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got Money
+			err := got.UnmarshalJSON([]byte(tt.data))
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("UnmarshalJSON(%s) = %v, %v, want %v, error %v", tt.data, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestUnmarshalField(t *testing.T) {
+	var body struct {
+		Price Money `json:"price"`
+	}
+	if err := json.Unmarshal([]byte(`{"price": {"amount": 45000, "currency": "KZT"}}`), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Price != New(45000, "KZT") {
+		t.Errorf("Price = %v", body.Price)
+	}
+	if err := json.Unmarshal([]byte(`{"price": {"amount": 1, "currency": "XXX"}}`), &body); !errors.Is(err, ErrUnknownCurrency) {
+		t.Errorf("error = %v, want %v", err, ErrUnknownCurrency)
+	}
+}
@@ -0,0 +1,127 @@
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"go_final/config"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	Bcrypt   = "bcrypt"
+	Argon2id = "argon2id"
+)
+
+var ErrUnknownHash = errors.New("unknown password hash format")
+
+// Hasher hashes new passwords with the configured algorithm and verifies
+// hashes made by any supported one.
+type Hasher interface {
+	Hash(string) (string, error)
+	// Verify reports whether password matches hash, and whether hash should
+	// be replaced because it was made with other settings than the current
+	// ones.
+	Verify(hash, password string) (ok bool, rehash bool)
+}
+
+type hasher struct {
+	cfg config.PasswordConfig
+}
+
+func NewHasher(cfg config.PasswordConfig) Hasher {
+	return &hasher{cfg: cfg}
+}
+
+func (h *hasher) Hash(password string) (string, error) {
+	switch h.cfg.Algorithm {
+	case Argon2id:
+		return h.hashArgon2id(password)
+	case Bcrypt:
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), h.cfg.BcryptCost)
+		return string(hash), err
+	default:
+		return "", fmt.Errorf("unsupported password hash algorithm %q", h.cfg.Algorithm)
+	}
+}
+
+func (h *hasher) Verify(hash, password string) (bool, bool) {
+	switch {
+	case strings.HasPrefix(hash, "$argon2id$"):
+		params, salt, key, err := decodeArgon2id(hash)
+		if err != nil {
+			return false, false
+		}
+		other := argon2.IDKey([]byte(password), salt, params.iterations, params.memory, params.parallelism, uint32(len(key)))
+		if subtle.ConstantTimeCompare(key, other) != 1 {
+			return false, false
+		}
+		return true, h.cfg.Algorithm != Argon2id || params != h.argon2Params()
+	case strings.HasPrefix(hash, "$2"):
+		if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) != nil {
+			return false, false
+		}
+		cost, err := bcrypt.Cost([]byte(hash))
+		return true, h.cfg.Algorithm != Bcrypt || err != nil || cost != h.cfg.BcryptCost
+	default:
+		return false, false
+	}
+}
+
+type argon2Params struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+}
+
+func (h *hasher) argon2Params() argon2Params {
+	return argon2Params{
+		memory:      uint32(h.cfg.Argon2Memory),
+		iterations:  uint32(h.cfg.Argon2Iterations),
+		parallelism: uint8(h.cfg.Argon2Parallelism),
+	}
+}
+
+// hashArgon2id encodes the hash in the PHC string format also used by the
+// reference implementation: $argon2id$v=19$m=...,t=...,p=...$salt$key.
+func (h *hasher) hashArgon2id(password string) (string, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	p := h.argon2Params()
+	key := argon2.IDKey([]byte(password), salt, p.iterations, p.memory, p.parallelism, 32)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, p.memory, p.iterations, p.parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+func decodeArgon2id(hash string) (argon2Params, []byte, []byte, error) {
+	var p argon2Params
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return p, nil, nil, ErrUnknownHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return p, nil, nil, ErrUnknownHash
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.memory, &p.iterations, &p.parallelism); err != nil {
+		return p, nil, nil, ErrUnknownHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return p, nil, nil, ErrUnknownHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return p, nil, nil, ErrUnknownHash
+	}
+	return p, salt, key, nil
+}
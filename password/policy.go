@@ -0,0 +1,113 @@
+package password
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"fmt"
+	"go_final/config"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// breached is a list of the most common passwords from public breach
+// corpora, used unless PASSWORD_BREACHED_LIST names another file.
+//
+//go:embed breached.txt
+var breached []byte
+
+const (
+	LowerClass  = "lower"
+	UpperClass  = "upper"
+	DigitClass  = "digit"
+	SymbolClass = "symbol"
+)
+
+var classes = map[string]struct {
+	match       func(rune) bool
+	description string
+}{
+	LowerClass:  {unicode.IsLower, "a lowercase letter"},
+	UpperClass:  {unicode.IsUpper, "an uppercase letter"},
+	DigitClass:  {unicode.IsDigit, "a digit"},
+	SymbolClass: {func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !unicode.IsSpace(r) }, "a symbol"},
+}
+
+// PolicyError lists every rule a password breaks.
+type PolicyError struct {
+	Violations []string
+}
+
+func (e *PolicyError) Error() string {
+	return "password " + strings.Join(e.Violations, ", ")
+}
+
+type Policy interface {
+	Validate(string) error
+}
+
+type policy struct {
+	cfg      config.PasswordConfig
+	breached map[string]bool
+}
+
+// NewPolicy builds the policy described by cfg and loads the breached
+// password list.
+func NewPolicy(cfg config.PasswordConfig) (Policy, error) {
+	for _, class := range cfg.RequiredClasses {
+		if _, ok := classes[class]; !ok {
+			return nil, fmt.Errorf("unknown password character class %q", class)
+		}
+	}
+
+	var list io.Reader = bytes.NewReader(breached)
+	if cfg.BreachedListPath != "" {
+		file, err := os.Open(cfg.BreachedListPath)
+		if err != nil {
+			return nil, fmt.Errorf("error while opening breached password list: %w", err)
+		}
+		defer file.Close()
+		list = file
+	}
+
+	p := &policy{cfg: cfg, breached: make(map[string]bool)}
+	scanner := bufio.NewScanner(list)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" && !strings.HasPrefix(line, "#") {
+			p.breached[strings.ToLower(line)] = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error while reading breached password list: %w", err)
+	}
+	return p, nil
+}
+
+// Validate returns a *PolicyError if password breaks any rule. Length is
+// counted in characters, the maximum in bytes as well since bcrypt only
+// uses the first 72.
+func (p *policy) Validate(password string) error {
+	var violations []string
+	if utf8.RuneCountInString(password) < p.cfg.MinLength {
+		violations = append(violations, fmt.Sprintf("must be at least %d characters long", p.cfg.MinLength))
+	}
+	if len(password) > p.cfg.MaxLength {
+		violations = append(violations, fmt.Sprintf("must be at most %d bytes long", p.cfg.MaxLength))
+	}
+	for _, class := range p.cfg.RequiredClasses {
+		if strings.IndexFunc(password, classes[class].match) < 0 {
+			violations = append(violations, "must contain "+classes[class].description)
+		}
+	}
+	if p.breached[strings.ToLower(password)] {
+		violations = append(violations, "is too common, it appears in known data breaches")
+	}
+
+	if len(violations) > 0 {
+		return &PolicyError{Violations: violations}
+	}
+	return nil
+}
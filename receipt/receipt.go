@@ -0,0 +1,198 @@
+// Package receipt lays out printable receipts of orders, as plain text for
+// thermal printers and as PDF.
+package receipt
+
+import (
+	"bytes"
+	"fmt"
+	"go_final/config"
+	"go_final/models"
+	"go_final/money"
+	"log"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-pdf/fpdf"
+)
+
+type Renderer interface {
+	// Text is the receipt as lines of at most the configured width, in
+	// characters.
+	Text(models.OrderDetails) []byte
+	PDF(models.OrderDetails) ([]byte, error)
+}
+
+type renderer struct {
+	title string
+	width int
+	// font is the TrueType font of PDF receipts, nil for Courier
+	font []byte
+}
+
+// New reads the font of cfg once, a missing font falls back to Courier.
+func New(cfg config.ReceiptConfig) Renderer {
+	r := &renderer{title: cfg.Title, width: cfg.Width}
+	if cfg.Font != "" {
+		font, err := os.ReadFile(cfg.Font)
+		if err != nil {
+			log.Printf("Error while loading the receipt font, PDF receipts use Courier: %v", err)
+		} else {
+			r.font = font
+		}
+	}
+	return r
+}
+
+func (r *renderer) Text(details models.OrderDetails) []byte {
+	return []byte(strings.Join(r.lines(details), "\n") + "\n")
+}
+
+const (
+	// pageWidth and margin are those of an 80 mm roll, in millimetres.
+	pageWidth = 80.0
+	margin    = 4.0
+	// monospaced glyphs are about 0.6 em wide
+	glyphWidth = 0.6
+	lineHeight = 1.25
+)
+
+// PDF draws the lines of the text receipt on a single page as long as they
+// need, with the font sized so that a line spans the roll.
+func (r *renderer) PDF(details models.OrderDetails) ([]byte, error) {
+	lines := r.lines(details)
+	fontSize := (pageWidth - 2*margin) / (float64(r.width) * glyphWidth)
+	height := fontSize * lineHeight
+	pageHeight := 2*margin + float64(len(lines))*height
+
+	pdf := fpdf.NewCustom(&fpdf.InitType{
+		OrientationStr: "P",
+		UnitStr:        "mm",
+		Size:           fpdf.SizeType{Wd: pageWidth, Ht: pageHeight},
+	})
+	pdf.SetMargins(margin, margin, margin)
+	pdf.SetAutoPageBreak(false, 0)
+	pdf.SetTitle(fmt.Sprintf("%s order #%d", r.title, details.ID), true)
+	pdf.SetCreationDate(details.UpdatedAt)
+
+	translate := func(s string) string { return s }
+	if r.font != nil {
+		pdf.AddUTF8FontFromBytes("receipt", "", r.font)
+		pdf.SetFont("receipt", "", 0)
+	} else {
+		pdf.SetFont("Courier", "", 0)
+		translate = pdf.UnicodeTranslatorFromDescriptor("")
+	}
+	// SetFontUnitSize takes the size in the unit of the document
+	pdf.SetFontUnitSize(fontSize)
+	pdf.AddPage()
+	for _, line := range lines {
+		pdf.CellFormat(0, height, translate(line), "", 1, "L", false, 0, "")
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// lines lays out the receipt: the header, every item with its modifiers and
+// the totals.
+func (r *renderer) lines(details models.OrderDetails) []string {
+	var lines []string
+	add := func(l ...string) { lines = append(lines, l...) }
+	rule := func(c string) { add(strings.Repeat(c, r.width)) }
+
+	add(r.center(r.title))
+	add(r.row(fmt.Sprintf("Order #%d", details.ID), details.CreatedAt.Format("2006-01-02 15:04"))...)
+	if details.User != nil {
+		add(r.wrap("Customer: " + details.User.Name)...)
+	}
+	add(r.row("Status:", string(details.Status))...)
+	rule("-")
+
+	for _, item := range details.Items {
+		name := item.ProductName
+		if item.Variant != "" {
+			name += " (" + item.Variant + ")"
+		}
+		add(r.wrap(name)...)
+		for _, modifier := range item.Modifiers {
+			add(r.row("  + "+modifier.Name, amount(modifier.PriceDelta))...)
+		}
+		add(r.row(fmt.Sprintf("  %d x %s", item.Quantity, amount(item.UnitPrice)), amount(item.Total))...)
+	}
+	rule("-")
+
+	add(r.row("Subtotal", amount(details.Subtotal))...)
+	if details.DiscountPercent > 0 {
+		add(r.row(fmt.Sprintf("Discount %d%%", details.DiscountPercent), "-"+amount(details.Discount))...)
+	}
+	if details.VATRate > 0 {
+		label := fmt.Sprintf("VAT %g%%", details.VATRate)
+		if details.VATIncluded {
+			label += " included"
+		}
+		add(r.row(label, amount(details.Tax))...)
+	}
+	rule("=")
+	add(r.row("TOTAL", details.Total.String())...)
+	rule("-")
+	add(r.center("Thank you!"))
+	return lines
+}
+
+// amount formats m without its currency, the total names it once.
+func amount(m money.Money) string {
+	return strings.TrimSuffix(m.String(), " "+m.Currency)
+}
+
+func (r *renderer) center(s string) string {
+	n := utf8.RuneCountInString(s)
+	if n >= r.width {
+		return s
+	}
+	return strings.Repeat(" ", (r.width-n)/2) + s
+}
+
+// row puts left and right on one line, right aligned, or right on a line of
+// its own when they do not fit.
+func (r *renderer) row(left, right string) []string {
+	gap := r.width - utf8.RuneCountInString(left) - utf8.RuneCountInString(right)
+	if gap >= 1 {
+		return []string{left + strings.Repeat(" ", gap) + right}
+	}
+	return append(r.wrap(left), strings.Repeat(" ", max(r.width-utf8.RuneCountInString(right), 0))+right)
+}
+
+// wrap breaks s at spaces into lines of the receipt width. Words longer
+// than a line are cut.
+func (r *renderer) wrap(s string) []string {
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(s) {
+		for utf8.RuneCountInString(word) > r.width {
+			if line != "" {
+				lines = append(lines, line)
+				line = ""
+			}
+			runes := []rune(word)
+			lines = append(lines, string(runes[:r.width]))
+			word = string(runes[r.width:])
+		}
+		switch {
+		case line == "":
+			line = word
+		case utf8.RuneCountInString(line)+1+utf8.RuneCountInString(word) <= r.width:
+			line += " " + word
+		default:
+			lines = append(lines, line)
+			line = word
+		}
+	}
+	if line != "" || len(lines) == 0 {
+		lines = append(lines, line)
+	}
+	return lines
+}
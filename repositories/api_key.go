@@ -0,0 +1,63 @@
+package repositories
+
+import (
+	"go_final/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// apiKeyTouchInterval limits how often the last use of a key is written, so
+// that busy integrations do not cause a write per request.
+const apiKeyTouchInterval = time.Minute
+
+type APIKeyRepository interface {
+	CreateAPIKey(models.APIKey) (models.APIKey, error)
+	GetAPIKeys() ([]models.APIKey, error)
+	GetAPIKeyByHash(string) (models.APIKey, error)
+	RevokeAPIKey(uint) error
+	TouchAPIKey(uint, time.Time) error
+}
+
+type apiKeyRepository struct {
+	connection *gorm.DB
+}
+
+func NewAPIKeyRepository(db *gorm.DB) APIKeyRepository {
+	return &apiKeyRepository{
+		connection: db,
+	}
+}
+
+func (db *apiKeyRepository) CreateAPIKey(key models.APIKey) (models.APIKey, error) {
+	return key, db.connection.Create(&key).Error
+}
+
+func (db *apiKeyRepository) GetAPIKeys() (keys []models.APIKey, err error) {
+	return keys, db.connection.Order("id").Find(&keys).Error
+}
+
+func (db *apiKeyRepository) GetAPIKeyByHash(hash string) (key models.APIKey, err error) {
+	return key, db.connection.First(&key, "key_hash = ?", hash).Error
+}
+
+// RevokeAPIKey fails with gorm.ErrRecordNotFound if there is no such key or
+// it was already revoked.
+func (db *apiKeyRepository) RevokeAPIKey(id uint) error {
+	result := db.connection.Model(&models.APIKey{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (db *apiKeyRepository) TouchAPIKey(id uint, at time.Time) error {
+	return db.connection.Model(&models.APIKey{}).
+		Where("id = ? AND (last_used_at IS NULL OR last_used_at < ?)", id, at.Add(-apiKeyTouchInterval)).
+		UpdateColumn("last_used_at", at).Error
+}
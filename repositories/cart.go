@@ -0,0 +1,295 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"go_final/config"
+	"go_final/models"
+	"go_final/money"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrCartEmpty = errors.New("cart is empty")
+	// ErrItemUnavailable is wrapped by the errors of cart items that cannot
+	// be ordered as they are any more, e.g. because the product was deleted.
+	ErrItemUnavailable = errors.New("item cannot be ordered")
+	// ErrOutOfStock is wrapped by the errors of cart items with too little
+	// stock left.
+	ErrOutOfStock = errors.New("not enough stock available")
+)
+
+// CartRepository keeps the carts of customers. Carts don't touch stock,
+// Checkout reserves it when it turns a cart into an order.
+type CartRepository interface {
+	GetCart(userID uint) (models.Cart, error)
+	// AddCartItem adds to the quantity of an item with the same choices
+	// when the cart has one.
+	AddCartItem(userID uint, item models.CartItemRequest) (models.CartItem, error)
+	UpdateCartItem(userID, itemID uint, item models.CartItemRequest) (models.CartItem, error)
+	DeleteCartItem(userID, itemID uint) error
+	ClearCart(userID uint) error
+	// Checkout orders every item of the cart at once and empties it. It
+	// fails without reserving anything if any item cannot be ordered.
+	Checkout(userID uint) (models.Order, error)
+}
+
+type cartRepository struct {
+	connection *gorm.DB
+	pricing    config.PricingConfig
+}
+
+func NewCartRepository(db *gorm.DB, pricing config.PricingConfig) CartRepository {
+	return &cartRepository{
+		connection: db,
+		pricing:    pricing,
+	}
+}
+
+func cartItems(tx *gorm.DB, userID uint) ([]models.CartItem, error) {
+	var items []models.CartItem
+	err := tx.Preload("Modifiers", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		Where("user_id = ?", userID).Order("id").Find(&items).Error
+	return items, err
+}
+
+// priceCartItem loads the product of item and prices the item.
+func priceCartItem(tx *gorm.DB, item models.CartItemRequest) (pricedItem, error) {
+	product, err := productLoader(tx)(item.ProductID)
+	if err != nil {
+		return pricedItem{}, err
+	}
+	return priceItem(product, item)
+}
+
+// productLoader loads products with their variants and modifier groups.
+func productLoader(tx *gorm.DB) func(productID uint) (models.Product, error) {
+	return func(productID uint) (product models.Product, err error) {
+		return product, withOptions(tx).First(&product, productID).Error
+	}
+}
+
+func (db *cartRepository) GetCart(userID uint) (models.Cart, error) {
+	items, err := cartItems(db.connection, userID)
+	if err != nil {
+		return models.Cart{}, err
+	}
+	return priceCart(items, db.pricing.Currency, productLoader(db.connection))
+}
+
+func (db *cartRepository) AddCartItem(userID uint, item models.CartItemRequest) (models.CartItem, error) {
+	var cartItem models.CartItem
+	err := db.connection.Transaction(func(tx *gorm.DB) error {
+		priced, err := priceCartItem(tx, item)
+		if err != nil {
+			return err
+		}
+		items, err := cartItems(tx, userID)
+		if err != nil {
+			return err
+		}
+		if existing, ok := sameCartItem(items, item); ok {
+			existing.Quantity += item.Quantity
+			cartItem = existing
+			return tx.Model(&existing).Update("quantity", existing.Quantity).Error
+		}
+		cartItem = newCartItem(userID, item, priced)
+		return tx.Create(&cartItem).Error
+	})
+	return cartItem, err
+}
+
+func (db *cartRepository) UpdateCartItem(userID, itemID uint, item models.CartItemRequest) (models.CartItem, error) {
+	var cartItem models.CartItem
+	err := db.connection.Transaction(func(tx *gorm.DB) error {
+		var existing models.CartItem
+		if err := tx.Where("user_id = ?", userID).First(&existing, itemID).Error; err != nil {
+			return err
+		}
+		item.ProductID = existing.ProductID
+		priced, err := priceCartItem(tx, item)
+		if err != nil {
+			return err
+		}
+		if err := tx.Where("cart_item_id = ?", existing.ID).Delete(&models.CartItemModifier{}).Error; err != nil {
+			return err
+		}
+		cartItem = newCartItem(userID, item, priced)
+		cartItem.Model = existing.Model
+		return tx.Save(&cartItem).Error
+	})
+	return cartItem, err
+}
+
+func (db *cartRepository) DeleteCartItem(userID, itemID uint) error {
+	result := db.connection.Unscoped().Where("user_id = ?", userID).Delete(&models.CartItem{}, itemID)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (db *cartRepository) ClearCart(userID uint) error {
+	return db.connection.Unscoped().Where("user_id = ?", userID).Delete(&models.CartItem{}).Error
+}
+
+func (db *cartRepository) Checkout(userID uint) (models.Order, error) {
+	var order models.Order
+	err := db.connection.Transaction(func(tx *gorm.DB) error {
+		items, err := cartItems(tx, userID)
+		if err != nil {
+			return err
+		}
+		if len(items) == 0 {
+			return ErrCartEmpty
+		}
+
+		order = models.Order{
+			UserID:      userID,
+			OrderStatus: models.PENDING,
+			VATRate:     int(db.pricing.VATBasisPoints()),
+			VATIncluded: db.pricing.VATIncluded,
+		}
+		if err := tx.Create(&order).Error; err != nil {
+			return err
+		}
+		for _, item := range items {
+			request := item.Request()
+			priced, err := priceForCheckout(request, productLoader(tx))
+			if err != nil {
+				return err
+			}
+			if err := reserveStock(tx, request.ProductID, priced, request.Quantity); err != nil {
+				return err
+			}
+			orderItem := newOrderItem(order.ID, request, priced)
+			if err := tx.Create(&orderItem).Error; err != nil {
+				return err
+			}
+		}
+		if err := updateTotals(tx, order.ID); err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("user_id = ?", userID).Delete(&models.CartItem{}).Error; err != nil {
+			return err
+		}
+		return tx.First(&order, order.ID).Error
+	})
+	return order, err
+}
+
+// reserveStock takes quantity off the stock of the variant of item, or of
+// its product without variants, unless there is not enough of it. The check
+// and the update are one statement so that concurrent checkouts cannot
+// both take the last items.
+func reserveStock(tx *gorm.DB, productID uint, item pricedItem, quantity int) error {
+	query := tx.Model(&models.Product{}).Where("id = ?", productID)
+	if item.variant != nil {
+		query = tx.Model(&models.ProductVariant{}).Where("id = ?", item.variant.ID)
+	}
+	result := query.Where("quantity >= ?", quantity).Update("quantity", gorm.Expr("quantity - ?", quantity))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w for: %s", ErrOutOfStock, item.name)
+	}
+	return nil
+}
+
+// priceForCheckout prices request with the product load returns. Items that
+// cannot be ordered fail with ErrItemUnavailable, other errors are those of
+// load.
+func priceForCheckout(request models.CartItemRequest, load func(productID uint) (models.Product, error)) (pricedItem, error) {
+	product, err := load(request.ProductID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return pricedItem{}, fmt.Errorf("%w: product %d is no longer available", ErrItemUnavailable, request.ProductID)
+	}
+	if err != nil {
+		return pricedItem{}, err
+	}
+	priced, err := priceItem(product, request)
+	if err != nil {
+		return pricedItem{}, fmt.Errorf("%w: %v", ErrItemUnavailable, err)
+	}
+	return priced, nil
+}
+
+// priceCart prices the items of a cart with the products load returns,
+// items that cannot be ordered any more are listed with the reason.
+func priceCart(items []models.CartItem, currency string, load func(productID uint) (models.Product, error)) (models.Cart, error) {
+	cart := models.Cart{Items: make([]models.CartLine, 0, len(items)), Subtotal: money.New(0, currency)}
+	for _, item := range items {
+		line := models.CartLine{
+			ID:        item.ID,
+			ProductID: item.ProductID,
+			VariantID: item.VariantID,
+			Modifiers: []models.OrderItemModifier{},
+			Quantity:  item.Quantity,
+		}
+		product, err := load(item.ProductID)
+		if err != nil {
+			return models.Cart{}, err
+		}
+		line.ProductName = product.Name
+		priced, err := priceItem(product, item.Request())
+		if err != nil {
+			line.Error = err.Error()
+			cart.Items = append(cart.Items, line)
+			continue
+		}
+		line.Variant = priced.variantName()
+		if priced.modifiers != nil {
+			line.Modifiers = priced.modifiers
+		}
+		line.UnitPrice = priced.price
+		line.InStock = priced.stock >= item.Quantity
+		if line.Total, err = priced.price.Mul(int64(item.Quantity)); err != nil {
+			return models.Cart{}, err
+		}
+		if cart.Subtotal, err = cart.Subtotal.Add(line.Total); err != nil {
+			return models.Cart{}, err
+		}
+		cart.Items = append(cart.Items, line)
+	}
+	return cart, nil
+}
+
+// sameCartItem finds the item of items with the choices of request.
+func sameCartItem(items []models.CartItem, request models.CartItemRequest) (models.CartItem, bool) {
+	for _, item := range items {
+		choices := item.Request()
+		if choices.ProductID == request.ProductID && choices.VariantID == request.VariantID &&
+			sameIDs(choices.ModifierIDs, request.ModifierIDs) {
+			return item, true
+		}
+	}
+	return models.CartItem{}, false
+}
+
+func newCartItem(userID uint, request models.CartItemRequest, priced pricedItem) models.CartItem {
+	item := models.CartItem{UserID: userID, ProductID: request.ProductID, VariantID: priced.variantID(), Quantity: request.Quantity}
+	for _, modifier := range priced.modifiers {
+		item.Modifiers = append(item.Modifiers, models.CartItemModifier{ModifierOptionID: *modifier.ModifierOptionID})
+	}
+	return item
+}
+
+// newOrderItem is the order item for request, with the names and prices of
+// priced.
+func newOrderItem(orderID uint, request models.CartItemRequest, priced pricedItem) models.OrderItems {
+	return models.OrderItems{
+		OrderID:     orderID,
+		ProductID:   request.ProductID,
+		ProductName: priced.product,
+		VariantID:   priced.variantID(),
+		VariantName: priced.variantName(),
+		Modifiers:   priced.modifiers,
+		Quantity:    request.Quantity,
+		Price:       priced.price,
+	}
+}
@@ -0,0 +1,122 @@
+package repositories
+
+import (
+	"errors"
+	"go_final/models"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrUnknownCategory = errors.New("unknown category")
+	ErrUnknownTag      = errors.New("unknown tag")
+)
+
+// CatalogRepository manages the categories and tags products are grouped
+// by.
+type CatalogRepository interface {
+	GetCategories() ([]models.Category, error)
+	GetCategory(uint) (models.Category, error)
+	CreateCategory(models.Category) (models.Category, error)
+	UpdateCategory(models.Category) (models.Category, error)
+	DeleteCategory(uint) error
+	GetTags() ([]models.Tag, error)
+	CreateTag(models.Tag) (models.Tag, error)
+	UpdateTag(models.Tag) (models.Tag, error)
+	DeleteTag(uint) error
+	GetMenu() ([]models.MenuSection, error)
+}
+
+type catalogRepository struct {
+	connection *gorm.DB
+}
+
+func NewCatalogRepository(db *gorm.DB) CatalogRepository {
+	return &catalogRepository{
+		connection: db,
+	}
+}
+
+func (db *catalogRepository) GetCategories() (categories []models.Category, err error) {
+	return categories, menuOrder(db.connection).Find(&categories).Error
+}
+
+func (db *catalogRepository) GetCategory(id uint) (category models.Category, err error) {
+	return category, db.connection.First(&category, id).Error
+}
+
+func (db *catalogRepository) CreateCategory(category models.Category) (models.Category, error) {
+	return category, db.connection.Create(&category).Error
+}
+
+func (db *catalogRepository) UpdateCategory(category models.Category) (models.Category, error) {
+	var existing models.Category
+	if err := db.connection.First(&existing, category.ID).Error; err != nil {
+		return category, err
+	}
+	category.CreatedAt = existing.CreatedAt
+	return category, db.connection.Model(&category).Select("name", "description", "position").Updates(&category).Error
+}
+
+func (db *catalogRepository) DeleteCategory(id uint) error {
+	result := db.connection.Unscoped().Delete(&models.Category{}, id)
+	if result.Error == nil && result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return result.Error
+}
+
+func (db *catalogRepository) GetTags() (tags []models.Tag, err error) {
+	return tags, db.connection.Order("name").Find(&tags).Error
+}
+
+func (db *catalogRepository) CreateTag(tag models.Tag) (models.Tag, error) {
+	return tag, db.connection.Create(&tag).Error
+}
+
+func (db *catalogRepository) UpdateTag(tag models.Tag) (models.Tag, error) {
+	var existing models.Tag
+	if err := db.connection.First(&existing, tag.ID).Error; err != nil {
+		return tag, err
+	}
+	tag.CreatedAt = existing.CreatedAt
+	return tag, db.connection.Model(&tag).Update("name", tag.Name).Error
+}
+
+func (db *catalogRepository) DeleteTag(id uint) error {
+	result := db.connection.Unscoped().Delete(&models.Tag{}, id)
+	if result.Error == nil && result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return result.Error
+}
+
+// GetMenu lists every category in menu order with its products sorted by
+// name. Products without a category are not on the menu.
+func (db *catalogRepository) GetMenu() ([]models.MenuSection, error) {
+	categories, err := db.GetCategories()
+	if err != nil {
+		return nil, err
+	}
+
+	sections := make([]models.MenuSection, 0, len(categories))
+	for _, category := range categories {
+		section := models.MenuSection{Category: category, Products: []models.Product{}}
+		err := withOptions(db.connection).Preload("Image").Preload("Tags", orderByName).
+			Joins("JOIN product_categories ON product_categories.product_id = products.id").
+			Where("product_categories.category_id = ?", category.ID).
+			Order("products.name").Find(&section.Products).Error
+		if err != nil {
+			return nil, err
+		}
+		sections = append(sections, section)
+	}
+	return sections, nil
+}
+
+func menuOrder(db *gorm.DB) *gorm.DB {
+	return db.Order("position, name")
+}
+
+func orderByName(db *gorm.DB) *gorm.DB {
+	return db.Order("name")
+}
@@ -2,29 +2,28 @@ package repositories
 
 import (
 	"fmt"
-	"go_final/models"
+	"go_final/config"
+
+	"github.com/glebarez/sqlite"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
-	"log"
-	"os"
 )
 
-func DB() *gorm.DB {
-	host := os.Getenv("HOST")
-	username := os.Getenv("POSTGRES_USER")
-	password := os.Getenv("POSTGRES_PASSWORD")
-	dbName := os.Getenv("POSTGRES_DB")
-	port := os.Getenv("PORT")
-
-	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=disable TimeZone=Asia/Shanghai", host, username, password, dbName, port)
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+func openDB(cfg config.DatabaseConfig) (*gorm.DB, error) {
+	var dialector gorm.Dialector
+	switch cfg.Driver {
+	case PostgresDriver:
+		dialector = postgres.Open(cfg.DSN())
+	case SQLiteDriver:
+		dialector = sqlite.Open(cfg.SQLitePath + "?_pragma=foreign_keys(1)")
+	default:
+		return nil, fmt.Errorf("unsupported database driver: %q", cfg.Driver)
+	}
 
+	db, err := gorm.Open(dialector, &gorm.Config{TranslateError: true})
 	if err != nil {
-		log.Fatal("Error while connecting to Database: " + err.Error())
-		return nil
+		return nil, fmt.Errorf("error while connecting to database: %w", err)
 	}
 
-	db.AutoMigrate(&models.User{}, &models.Product{}, &models.Order{}, &models.OrderItems{})
-
-	return db
+	return db, nil
 }
@@ -0,0 +1,461 @@
+package repositories
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+// ErrInvalidQuery is wrapped by every error caused by the list parameters a
+// client sent, as opposed to storage errors.
+var ErrInvalidQuery = errors.New("invalid list query")
+
+type FieldKind int
+
+const (
+	StringField FieldKind = iota
+	IntField
+	BoolField
+	TimeField
+)
+
+// Field is a field of a list that can be sorted and filtered on. Column is a
+// column or SQL expression for the database stores, Value extracts the same
+// value from an item for the memory store.
+//
+// A field with Values instead of Value is a set, such as the tags of a
+// product. It can only be filtered for membership and not sorted by, Column
+// is then the whole SQL condition taking the value as its only argument.
+type Field[T any] struct {
+	Column string
+	Kind   FieldKind
+	Value  func(T) any
+	Values func(T) []any
+}
+
+// ListSpec names the fields of a list. It must contain "id", which breaks
+// ties between items that sort equal.
+type ListSpec[T any] struct {
+	Fields map[string]Field[T]
+}
+
+type FilterOp string
+
+const (
+	OpEq  FilterOp = "eq"
+	OpNe  FilterOp = "ne"
+	OpLt  FilterOp = "lt"
+	OpLte FilterOp = "lte"
+	OpGt  FilterOp = "gt"
+	OpGte FilterOp = "gte"
+)
+
+var filterSQL = map[FilterOp]string{OpEq: "=", OpNe: "<>", OpLt: "<", OpLte: "<=", OpGt: ">", OpGte: ">="}
+
+type Filter struct {
+	Field string
+	Op    FilterOp
+	Value any
+}
+
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+// ListQuery selects one page of a list. After holds the sort values of the
+// last item of the previous page when paging by cursor, Offset is used
+// otherwise.
+type ListQuery struct {
+	Limit   int
+	Offset  int
+	Sort    []SortField
+	Filters []Filter
+	After   []any
+}
+
+// Page is the envelope of every list response. NextCursor is empty on the
+// last page.
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	Total      int64  `json:"total"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// cursor is encoded into an opaque string, Sort makes sure it is only used
+// with the order it was made for.
+type cursor struct {
+	Sort   string `json:"s"`
+	Values []any  `json:"v"`
+}
+
+// ParseListQuery reads limit, offset, cursor, sort and field filters from
+// query parameters. Sort is a comma separated list of fields, descending when
+// prefixed with a minus. A filter is field=value, or field_op=value with op
+// one of ne, lt, lte, gt and gte.
+func ParseListQuery[T any](values url.Values, spec ListSpec[T]) (ListQuery, error) {
+	query := ListQuery{Limit: DefaultLimit}
+	for key, list := range values {
+		value := list[len(list)-1]
+		var err error
+		switch key {
+		case "limit":
+			query.Limit, err = strconv.Atoi(value)
+			if err == nil && (query.Limit < 1 || query.Limit > MaxLimit) {
+				err = fmt.Errorf("must be between 1 and %d", MaxLimit)
+			}
+		case "offset":
+			query.Offset, err = strconv.Atoi(value)
+			if err == nil && query.Offset < 0 {
+				err = errors.New("must not be negative")
+			}
+		case "sort", "cursor":
+			// handled below, the cursor depends on the sort
+		default:
+			var filter Filter
+			filter, err = parseFilter(key, value, spec)
+			query.Filters = append(query.Filters, filter)
+		}
+		if err != nil {
+			return query, fmt.Errorf("%w: %s: %v", ErrInvalidQuery, key, err)
+		}
+	}
+
+	sortParam := values.Get("sort")
+	if sortParam != "" {
+		for _, name := range strings.Split(sortParam, ",") {
+			field := SortField{Field: strings.TrimPrefix(name, "-"), Desc: strings.HasPrefix(name, "-")}
+			if f, ok := spec.Fields[field.Field]; !ok || f.Values != nil {
+				return query, fmt.Errorf("%w: cannot sort by %q", ErrInvalidQuery, field.Field)
+			}
+			query.Sort = append(query.Sort, field)
+		}
+	}
+	query.Sort = withTieBreaker(query.Sort)
+
+	if encoded := values.Get("cursor"); encoded != "" {
+		if values.Has("offset") {
+			return query, fmt.Errorf("%w: cursor and offset cannot be combined", ErrInvalidQuery)
+		}
+		after, err := decodeCursor(encoded, sortKey(query.Sort), query.Sort, spec)
+		if err != nil {
+			return query, fmt.Errorf("%w: cursor: %v", ErrInvalidQuery, err)
+		}
+		query.After = after
+	}
+	return query, nil
+}
+
+// Where adds a filter that the client cannot lift, e.g. to the caller's own
+// items.
+func (q ListQuery) Where(field string, value any) ListQuery {
+	q.Filters = append(append([]Filter(nil), q.Filters...), Filter{Field: field, Op: OpEq, Value: value})
+	return q
+}
+
+func parseFilter[T any](key, value string, spec ListSpec[T]) (Filter, error) {
+	filter := Filter{Field: key, Op: OpEq}
+	if _, ok := spec.Fields[key]; !ok {
+		if i := strings.LastIndex(key, "_"); i > 0 {
+			filter.Field, filter.Op = key[:i], FilterOp(key[i+1:])
+		}
+	}
+	field, ok := spec.Fields[filter.Field]
+	if _, known := filterSQL[filter.Op]; !ok || !known {
+		return filter, errors.New("unknown filter")
+	}
+	if (field.Kind == BoolField || field.Values != nil) && filter.Op != OpEq {
+		return filter, errors.New("only equality applies to this field")
+	}
+	var err error
+	filter.Value, err = parseValue(field.Kind, value)
+	return filter, err
+}
+
+func parseValue(kind FieldKind, value string) (any, error) {
+	switch kind {
+	case IntField:
+		return strconv.ParseInt(value, 10, 64)
+	case BoolField:
+		return strconv.ParseBool(value)
+	case TimeField:
+		return time.Parse(time.RFC3339Nano, value)
+	default:
+		return value, nil
+	}
+}
+
+func withTieBreaker(fields []SortField) []SortField {
+	for _, field := range fields {
+		if field.Field == "id" {
+			return fields
+		}
+	}
+	return append(fields, SortField{Field: "id"})
+}
+
+func sortKey(fields []SortField) string {
+	names := make([]string, len(fields))
+	for i, field := range fields {
+		names[i] = field.Field
+		if field.Desc {
+			names[i] = "-" + names[i]
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+func encodeCursor[T any](item T, fields []SortField, spec ListSpec[T]) string {
+	c := cursor{Sort: sortKey(fields)}
+	for _, field := range fields {
+		c.Values = append(c.Values, spec.Fields[field.Field].Value(item))
+	}
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor[T any](encoded, key string, fields []SortField, spec ListSpec[T]) ([]any, error) {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, errors.New("malformed")
+	}
+	var c cursor
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&c); err != nil || len(c.Values) != len(fields) {
+		return nil, errors.New("malformed")
+	}
+	if c.Sort != key {
+		return nil, errors.New("was made for another sort order")
+	}
+
+	// JSON turned the values into strings, numbers and booleans
+	values := make([]any, len(fields))
+	for i, field := range fields {
+		values[i], err = parseValue(spec.Fields[field.Field].Kind, fmt.Sprint(c.Values[i]))
+		if err != nil {
+			return nil, errors.New("malformed")
+		}
+	}
+	return values, nil
+}
+
+// paginate runs query against db, which must already select the model of
+// the list.
+func paginate[T any](db *gorm.DB, spec ListSpec[T], query ListQuery) (Page[T], error) {
+	page := Page[T]{Items: []T{}}
+	for _, filter := range query.Filters {
+		field := spec.Fields[filter.Field]
+		if field.Values != nil {
+			db = db.Where(field.Column, filter.Value)
+			continue
+		}
+		db = db.Where(fmt.Sprintf("(%s) %s ?", field.Column, filterSQL[filter.Op]), filter.Value)
+	}
+	db = db.Session(&gorm.Session{})
+	if err := db.Count(&page.Total).Error; err != nil {
+		return page, err
+	}
+
+	if query.After != nil {
+		condition, args := keysetCondition(spec, query.Sort, query.After)
+		db = db.Where(condition, args...)
+	} else {
+		db = db.Offset(query.Offset)
+	}
+	for _, field := range query.Sort {
+		column := spec.Fields[field.Field].Column
+		if field.Desc {
+			column += " DESC"
+		}
+		db = db.Order(column)
+	}
+	if err := db.Limit(query.Limit + 1).Find(&page.Items).Error; err != nil {
+		return page, err
+	}
+	return trimPage(page, spec, query), nil
+}
+
+// keysetCondition selects the items sorting after the cursor values: those
+// greater on the first field, or equal on it and greater on the next and so
+// on.
+func keysetCondition[T any](spec ListSpec[T], fields []SortField, after []any) (string, []any) {
+	var alternatives []string
+	var args []any
+	for i := range fields {
+		var parts []string
+		for j := 0; j <= i; j++ {
+			op := "="
+			if j == i {
+				op = ">"
+				if fields[j].Desc {
+					op = "<"
+				}
+			}
+			parts = append(parts, fmt.Sprintf("(%s) %s ?", spec.Fields[fields[j].Field].Column, op))
+			args = append(args, after[j])
+		}
+		alternatives = append(alternatives, "("+strings.Join(parts, " AND ")+")")
+	}
+	return strings.Join(alternatives, " OR "), args
+}
+
+// paginateSlice is paginate for the memory store.
+func paginateSlice[T any](items []T, spec ListSpec[T], query ListQuery) Page[T] {
+	filtered := []T{}
+	for _, item := range items {
+		if matches(item, spec, query.Filters) {
+			filtered = append(filtered, item)
+		}
+	}
+	page := Page[T]{Total: int64(len(filtered))}
+
+	sort.SliceStable(filtered, func(i, j int) bool {
+		return compareItems(spec, query.Sort, sortValues(filtered[i], spec, query.Sort), sortValues(filtered[j], spec, query.Sort)) < 0
+	})
+
+	start := query.Offset
+	if query.After != nil {
+		start = sort.Search(len(filtered), func(i int) bool {
+			return compareItems(spec, query.Sort, sortValues(filtered[i], spec, query.Sort), query.After) > 0
+		})
+	}
+	if start > len(filtered) {
+		start = len(filtered)
+	}
+	end := start + query.Limit + 1
+	if end > len(filtered) {
+		end = len(filtered)
+	}
+	page.Items = filtered[start:end]
+	return trimPage(page, spec, query)
+}
+
+// trimPage drops the extra item fetched to find out whether there is a next
+// page.
+func trimPage[T any](page Page[T], spec ListSpec[T], query ListQuery) Page[T] {
+	if len(page.Items) > query.Limit {
+		page.Items = page.Items[:query.Limit]
+		page.NextCursor = encodeCursor(page.Items[query.Limit-1], query.Sort, spec)
+	}
+	return page
+}
+
+func matches[T any](item T, spec ListSpec[T], filters []Filter) bool {
+	for _, filter := range filters {
+		field := spec.Fields[filter.Field]
+		if field.Values != nil {
+			if !contains(field.Values(item), filter.Value) {
+				return false
+			}
+			continue
+		}
+		c := compareValues(field.Value(item), filter.Value)
+		var ok bool
+		switch filter.Op {
+		case OpEq:
+			ok = c == 0
+		case OpNe:
+			ok = c != 0
+		case OpLt:
+			ok = c < 0
+		case OpLte:
+			ok = c <= 0
+		case OpGt:
+			ok = c > 0
+		case OpGte:
+			ok = c >= 0
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func contains(values []any, value any) bool {
+	for _, v := range values {
+		if compareValues(v, value) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func sortValues[T any](item T, spec ListSpec[T], fields []SortField) []any {
+	values := make([]any, len(fields))
+	for i, field := range fields {
+		values[i] = spec.Fields[field.Field].Value(item)
+	}
+	return values
+}
+
+func compareItems[T any](spec ListSpec[T], fields []SortField, a, b []any) int {
+	for i, field := range fields {
+		if c := compareValues(a[i], b[i]); c != 0 {
+			if field.Desc {
+				return -c
+			}
+			return c
+		}
+	}
+	return 0
+}
+
+// compareValues compares two values of the same field kind. Integers of any
+// size are compared as int64.
+func compareValues(a, b any) int {
+	switch a := a.(type) {
+	case string:
+		return strings.Compare(a, b.(string))
+	case bool:
+		b := b.(bool)
+		switch {
+		case a == b:
+			return 0
+		case !a:
+			return -1
+		default:
+			return 1
+		}
+	case time.Time:
+		return a.Compare(b.(time.Time))
+	default:
+		x, y := toInt64(a), toInt64(b)
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		default:
+			return 0
+		}
+	}
+}
+
+func toInt64(v any) int64 {
+	switch v := v.(type) {
+	case int:
+		return int64(v)
+	case int64:
+		return v
+	case uint:
+		return int64(v)
+	default:
+		return 0
+	}
+}
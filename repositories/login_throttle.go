@@ -0,0 +1,63 @@
+package repositories
+
+import (
+	"go_final/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type LoginThrottleRepository interface {
+	GetLoginThrottle(string) (models.LoginThrottle, error)
+	RecordLoginFailure(string, time.Time) (models.LoginThrottle, error)
+	LockLogin(string, time.Time) error
+	ResetLoginThrottle(string) error
+}
+
+type loginThrottleRepository struct {
+	connection *gorm.DB
+}
+
+func NewLoginThrottleRepository(db *gorm.DB) LoginThrottleRepository {
+	return &loginThrottleRepository{
+		connection: db,
+	}
+}
+
+// GetLoginThrottle returns an empty throttle for subjects without failures.
+func (db *loginThrottleRepository) GetLoginThrottle(subject string) (models.LoginThrottle, error) {
+	throttle := models.LoginThrottle{Subject: subject}
+	err := db.connection.Where("subject = ?", subject).Limit(1).Find(&throttle).Error
+	return throttle, err
+}
+
+// RecordLoginFailure increments the failure count of subject in a single
+// statement, so concurrent attempts are all counted.
+func (db *loginThrottleRepository) RecordLoginFailure(subject string, at time.Time) (models.LoginThrottle, error) {
+	throttle := models.LoginThrottle{Subject: subject, Failures: 1, LastFailureAt: at}
+	err := db.connection.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "subject"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"failures":        gorm.Expr("login_throttles.failures + 1"),
+				"last_failure_at": at,
+			}),
+		}).Create(&throttle).Error
+		if err != nil {
+			return err
+		}
+		return tx.First(&throttle, "subject = ?", subject).Error
+	})
+	return throttle, err
+}
+
+func (db *loginThrottleRepository) LockLogin(subject string, until time.Time) error {
+	return db.connection.Model(&models.LoginThrottle{}).
+		Where("subject = ?", subject).
+		Update("locked_until", until).Error
+}
+
+func (db *loginThrottleRepository) ResetLoginThrottle(subject string) error {
+	return db.connection.Where("subject = ?", subject).Delete(&models.LoginThrottle{}).Error
+}
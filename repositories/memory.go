@@ -0,0 +1,147 @@
+package repositories
+
+import (
+	"go_final/config"
+	"go_final/models"
+	"sort"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// memoryStore keeps every table in process memory. It is meant for local
+// development and tests, data is lost when the process exits.
+type memoryStore struct {
+	mu      sync.RWMutex
+	pricing config.PricingConfig
+	memoryTables
+}
+
+// memoryTables are the tables of the memory store. Their rows are values
+// that are replaced rather than changed in place, so copying the maps is
+// enough for a transaction to roll them back.
+type memoryTables struct {
+	users      map[uint]models.User
+	products   map[uint]models.Product
+	orders     map[uint]models.Order
+	orderItems map[uint]models.OrderItems
+	// cartItems hold their modifiers
+	cartItems map[uint]models.CartItem
+	sequences map[string]uint
+
+	categories map[uint]models.Category
+	tags       map[uint]models.Tag
+	// productCategories and productTags map product ids to the ids of
+	// their categories and tags
+	productCategories map[uint][]uint
+	productTags       map[uint][]uint
+	productImages     map[uint]models.ProductImage
+	variants          map[uint]models.ProductVariant
+	// modifierGroups hold their options
+	modifierGroups map[uint]models.ModifierGroup
+
+	// deletedUsers holds soft deleted users, only the lookups of deleted
+	// users see them
+	deletedUsers map[uint]models.User
+
+	refreshTokens map[uint]models.RefreshToken
+	revokedTokens map[string]models.RevokedToken
+	tokenCutoffs  map[uint]models.UserTokenCutoff
+	userTokens    map[uint]models.UserToken
+
+	loginThrottles map[string]models.LoginThrottle
+	userMFA        map[uint]models.UserMFA
+	recoveryCodes  map[uint]models.RecoveryCode
+	apiKeys        map[uint]models.APIKey
+}
+
+func NewMemoryStore(pricing config.PricingConfig) Store {
+	return &memoryStore{pricing: pricing, memoryTables: memoryTables{}.clone()}
+}
+
+// clone copies every table, the zero memoryTables clones into empty ones.
+func (t memoryTables) clone() memoryTables {
+	return memoryTables{
+		users:      cloneMap(t.users),
+		products:   cloneMap(t.products),
+		orders:     cloneMap(t.orders),
+		orderItems: cloneMap(t.orderItems),
+		cartItems:  cloneMap(t.cartItems),
+		sequences:  cloneMap(t.sequences),
+
+		categories:        cloneMap(t.categories),
+		tags:              cloneMap(t.tags),
+		productCategories: cloneMap(t.productCategories),
+		productTags:       cloneMap(t.productTags),
+		productImages:     cloneMap(t.productImages),
+		variants:          cloneMap(t.variants),
+		modifierGroups:    cloneMap(t.modifierGroups),
+
+		deletedUsers: cloneMap(t.deletedUsers),
+
+		refreshTokens: cloneMap(t.refreshTokens),
+		revokedTokens: cloneMap(t.revokedTokens),
+		tokenCutoffs:  cloneMap(t.tokenCutoffs),
+		userTokens:    cloneMap(t.userTokens),
+
+		loginThrottles: cloneMap(t.loginThrottles),
+		userMFA:        cloneMap(t.userMFA),
+		recoveryCodes:  cloneMap(t.recoveryCodes),
+		apiKeys:        cloneMap(t.apiKeys),
+	}
+}
+
+func (s *memoryStore) Users() UserRepository       { return &memoryUserRepository{store: s} }
+func (s *memoryStore) Products() ProductRepository { return &memoryProductRepository{store: s} }
+func (s *memoryStore) Orders() OrderRepository     { return &memoryOrderRepository{store: s} }
+func (s *memoryStore) Carts() CartRepository       { return &memoryCartRepository{store: s} }
+func (s *memoryStore) Tokens() TokenRepository     { return &memoryTokenRepository{store: s} }
+func (s *memoryStore) Close() error                { return nil }
+func (s *memoryStore) LoginThrottles() LoginThrottleRepository {
+	return &memoryLoginThrottleRepository{store: s}
+}
+func (s *memoryStore) MFA() MFARepository         { return &memoryMFARepository{store: s} }
+func (s *memoryStore) APIKeys() APIKeyRepository  { return &memoryAPIKeyRepository{store: s} }
+func (s *memoryStore) Catalog() CatalogRepository { return &memoryCatalogRepository{store: s} }
+
+func (s *memoryStore) nextID(table string) uint {
+	s.sequences[table]++
+	return s.sequences[table]
+}
+
+func (s *memoryStore) newModel(table string) gorm.Model {
+	now := time.Now()
+	return gorm.Model{ID: s.nextID(table), CreatedAt: now, UpdatedAt: now}
+}
+
+// transaction runs fn under the write lock and rolls every table back to its
+// previous state if fn fails.
+func (s *memoryStore) transaction(fn func() error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snapshot := s.memoryTables.clone()
+	if err := fn(); err != nil {
+		s.memoryTables = snapshot
+		return err
+	}
+	return nil
+}
+
+func cloneMap[K comparable, V any](m map[K]V) map[K]V {
+	clone := make(map[K]V, len(m))
+	for k, v := range m {
+		clone[k] = v
+	}
+	return clone
+}
+
+func sortedIDs[V any](m map[uint]V) []uint {
+	ids := make([]uint, 0, len(m))
+	for id := range m {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
@@ -0,0 +1,71 @@
+package repositories
+
+import (
+	"go_final/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type memoryAPIKeyRepository struct {
+	store *memoryStore
+}
+
+func (r *memoryAPIKeyRepository) CreateAPIKey(key models.APIKey) (models.APIKey, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	key.Model = r.store.newModel("api_keys")
+	r.store.apiKeys[key.ID] = key
+	return key, nil
+}
+
+func (r *memoryAPIKeyRepository) GetAPIKeys() ([]models.APIKey, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	keys := make([]models.APIKey, 0, len(r.store.apiKeys))
+	for _, id := range sortedIDs(r.store.apiKeys) {
+		keys = append(keys, r.store.apiKeys[id])
+	}
+	return keys, nil
+}
+
+func (r *memoryAPIKeyRepository) GetAPIKeyByHash(hash string) (models.APIKey, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, key := range r.store.apiKeys {
+		if key.KeyHash == hash {
+			return key, nil
+		}
+	}
+	return models.APIKey{}, gorm.ErrRecordNotFound
+}
+
+func (r *memoryAPIKeyRepository) RevokeAPIKey(id uint) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	key, ok := r.store.apiKeys[id]
+	if !ok || key.RevokedAt != nil {
+		return gorm.ErrRecordNotFound
+	}
+	now := time.Now()
+	key.RevokedAt = &now
+	key.UpdatedAt = now
+	r.store.apiKeys[id] = key
+	return nil
+}
+
+func (r *memoryAPIKeyRepository) TouchAPIKey(id uint, at time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	key, ok := r.store.apiKeys[id]
+	if ok && (key.LastUsedAt == nil || key.LastUsedAt.Before(at.Add(-apiKeyTouchInterval))) {
+		key.LastUsedAt = &at
+		r.store.apiKeys[id] = key
+	}
+	return nil
+}
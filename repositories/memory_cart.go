@@ -0,0 +1,189 @@
+package repositories
+
+import (
+	"fmt"
+	"go_final/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type memoryCartRepository struct {
+	store *memoryStore
+}
+
+// productWithOptions returns a product with its variants and modifier
+// groups.
+func (s *memoryStore) productWithOptions(productID uint) (models.Product, error) {
+	product, ok := s.products[productID]
+	if !ok {
+		return models.Product{}, gorm.ErrRecordNotFound
+	}
+	product.Variants, product.ModifierGroups = s.productOptions(productID)
+	return product, nil
+}
+
+// priceCartItem prices item against its product, like the gorm repository.
+func (s *memoryStore) priceCartItem(item models.CartItemRequest) (pricedItem, error) {
+	product, err := s.productWithOptions(item.ProductID)
+	if err != nil {
+		return pricedItem{}, err
+	}
+	return priceItem(product, item)
+}
+
+// cartOf returns the items of the cart of a user in the order they were
+// added.
+func (s *memoryStore) cartOf(userID uint) []models.CartItem {
+	items := []models.CartItem{}
+	for _, id := range sortedIDs(s.cartItems) {
+		if item := s.cartItems[id]; item.UserID == userID {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// saveCartItem stores item with new ids for it and its modifiers.
+func (s *memoryStore) saveCartItem(item models.CartItem) models.CartItem {
+	if item.ID == 0 {
+		item.Model = s.newModel("cart_items")
+	}
+	for i := range item.Modifiers {
+		item.Modifiers[i].ID = s.nextID("cart_item_modifiers")
+		item.Modifiers[i].CartItemID = item.ID
+	}
+	s.cartItems[item.ID] = item
+	return item
+}
+
+func (r *memoryCartRepository) GetCart(userID uint) (models.Cart, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	return priceCart(r.store.cartOf(userID), r.store.pricing.Currency, r.store.productWithOptions)
+}
+
+func (r *memoryCartRepository) AddCartItem(userID uint, item models.CartItemRequest) (models.CartItem, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	priced, err := r.store.priceCartItem(item)
+	if err != nil {
+		return models.CartItem{}, err
+	}
+	if existing, ok := sameCartItem(r.store.cartOf(userID), item); ok {
+		existing.Quantity += item.Quantity
+		existing.UpdatedAt = time.Now()
+		r.store.cartItems[existing.ID] = existing
+		return existing, nil
+	}
+	return r.store.saveCartItem(newCartItem(userID, item, priced)), nil
+}
+
+func (r *memoryCartRepository) UpdateCartItem(userID, itemID uint, item models.CartItemRequest) (models.CartItem, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	existing, ok := r.store.cartItems[itemID]
+	if !ok || existing.UserID != userID {
+		return models.CartItem{}, gorm.ErrRecordNotFound
+	}
+	item.ProductID = existing.ProductID
+	priced, err := r.store.priceCartItem(item)
+	if err != nil {
+		return models.CartItem{}, err
+	}
+	cartItem := newCartItem(userID, item, priced)
+	cartItem.Model = existing.Model
+	cartItem.UpdatedAt = time.Now()
+	return r.store.saveCartItem(cartItem), nil
+}
+
+func (r *memoryCartRepository) DeleteCartItem(userID, itemID uint) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if item, ok := r.store.cartItems[itemID]; !ok || item.UserID != userID {
+		return gorm.ErrRecordNotFound
+	}
+	delete(r.store.cartItems, itemID)
+	return nil
+}
+
+func (r *memoryCartRepository) ClearCart(userID uint) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	r.store.clearCart(userID)
+	return nil
+}
+
+func (s *memoryStore) clearCart(userID uint) {
+	for id, item := range s.cartItems {
+		if item.UserID == userID {
+			delete(s.cartItems, id)
+		}
+	}
+}
+
+func (r *memoryCartRepository) Checkout(userID uint) (models.Order, error) {
+	var order models.Order
+	err := r.store.transaction(func() error {
+		items := r.store.cartOf(userID)
+		if len(items) == 0 {
+			return ErrCartEmpty
+		}
+
+		order = models.Order{
+			Model:       r.store.newModel("orders"),
+			UserID:      userID,
+			OrderStatus: models.PENDING,
+			VATRate:     int(r.store.pricing.VATBasisPoints()),
+			VATIncluded: r.store.pricing.VATIncluded,
+		}
+		r.store.orders[order.ID] = order
+		for _, item := range items {
+			request := item.Request()
+			priced, err := priceForCheckout(request, r.store.productWithOptions)
+			if err != nil {
+				return err
+			}
+			if err := r.store.reserveStock(request.ProductID, priced, request.Quantity); err != nil {
+				return err
+			}
+			orderItem := newOrderItem(order.ID, request, priced)
+			orderItem.Model = r.store.newModel("order_items")
+			orderItem.Modifiers = r.store.newModifiers(orderItem.ID, orderItem.Modifiers)
+			r.store.orderItems[orderItem.ID] = orderItem
+		}
+		if err := r.store.updateTotals(order.ID); err != nil {
+			return err
+		}
+		r.store.clearCart(userID)
+		order = r.store.orders[order.ID]
+		return nil
+	})
+	return order, err
+}
+
+// reserveStock takes quantity off the stock of item, like the gorm
+// repository.
+func (s *memoryStore) reserveStock(productID uint, item pricedItem, quantity int) error {
+	if item.variant != nil {
+		variant, ok := s.variants[item.variant.ID]
+		if !ok || variant.Quantity < quantity {
+			return fmt.Errorf("%w for: %s", ErrOutOfStock, item.name)
+		}
+		variant.Quantity -= quantity
+		s.variants[variant.ID] = variant
+		return nil
+	}
+	product, ok := s.products[productID]
+	if !ok || product.Quantity < quantity {
+		return fmt.Errorf("%w for: %s", ErrOutOfStock, item.name)
+	}
+	product.Quantity -= quantity
+	s.products[productID] = product
+	return nil
+}
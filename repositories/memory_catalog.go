@@ -0,0 +1,234 @@
+package repositories
+
+import (
+	"go_final/models"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type memoryCatalogRepository struct {
+	store *memoryStore
+}
+
+func (r *memoryCatalogRepository) categoryNameTaken(name string, exceptID uint) bool {
+	for _, category := range r.store.categories {
+		if category.Name == name && category.ID != exceptID {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *memoryCatalogRepository) tagNameTaken(name string, exceptID uint) bool {
+	for _, tag := range r.store.tags {
+		if tag.Name == name && tag.ID != exceptID {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *memoryCatalogRepository) GetCategories() ([]models.Category, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	return r.store.sortedCategories(sortedIDs(r.store.categories)), nil
+}
+
+func (r *memoryCatalogRepository) GetCategory(id uint) (models.Category, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	category, ok := r.store.categories[id]
+	if !ok {
+		return category, gorm.ErrRecordNotFound
+	}
+	return category, nil
+}
+
+func (r *memoryCatalogRepository) CreateCategory(category models.Category) (models.Category, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if r.categoryNameTaken(category.Name, 0) {
+		return category, gorm.ErrDuplicatedKey
+	}
+	category.Model = r.store.newModel("categories")
+	r.store.categories[category.ID] = category
+	return category, nil
+}
+
+func (r *memoryCatalogRepository) UpdateCategory(category models.Category) (models.Category, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	existing, ok := r.store.categories[category.ID]
+	if !ok {
+		return category, gorm.ErrRecordNotFound
+	}
+	if r.categoryNameTaken(category.Name, category.ID) {
+		return category, gorm.ErrDuplicatedKey
+	}
+	category.CreatedAt = existing.CreatedAt
+	category.UpdatedAt = time.Now()
+	r.store.categories[category.ID] = category
+	return category, nil
+}
+
+func (r *memoryCatalogRepository) DeleteCategory(id uint) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.categories[id]; !ok {
+		return gorm.ErrRecordNotFound
+	}
+	delete(r.store.categories, id)
+	for productID, ids := range r.store.productCategories {
+		r.store.productCategories[productID] = without(ids, id)
+	}
+	return nil
+}
+
+func (r *memoryCatalogRepository) GetTags() ([]models.Tag, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	return r.store.sortedTags(sortedIDs(r.store.tags)), nil
+}
+
+func (r *memoryCatalogRepository) CreateTag(tag models.Tag) (models.Tag, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if r.tagNameTaken(tag.Name, 0) {
+		return tag, gorm.ErrDuplicatedKey
+	}
+	tag.Model = r.store.newModel("tags")
+	r.store.tags[tag.ID] = tag
+	return tag, nil
+}
+
+func (r *memoryCatalogRepository) UpdateTag(tag models.Tag) (models.Tag, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	existing, ok := r.store.tags[tag.ID]
+	if !ok {
+		return tag, gorm.ErrRecordNotFound
+	}
+	if r.tagNameTaken(tag.Name, tag.ID) {
+		return tag, gorm.ErrDuplicatedKey
+	}
+	tag.CreatedAt = existing.CreatedAt
+	tag.UpdatedAt = time.Now()
+	r.store.tags[tag.ID] = tag
+	return tag, nil
+}
+
+func (r *memoryCatalogRepository) DeleteTag(id uint) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.tags[id]; !ok {
+		return gorm.ErrRecordNotFound
+	}
+	delete(r.store.tags, id)
+	for productID, ids := range r.store.productTags {
+		r.store.productTags[productID] = without(ids, id)
+	}
+	return nil
+}
+
+func (r *memoryCatalogRepository) GetMenu() ([]models.MenuSection, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	categories := r.store.sortedCategories(sortedIDs(r.store.categories))
+	sections := make([]models.MenuSection, 0, len(categories))
+	for _, category := range categories {
+		section := models.MenuSection{Category: category, Products: []models.Product{}}
+		for productID, ids := range r.store.productCategories {
+			if containsID(ids, category.ID) {
+				section.Products = append(section.Products, r.store.productWithLinks(r.store.products[productID]))
+			}
+		}
+		sort.Slice(section.Products, func(i, j int) bool { return section.Products[i].Name < section.Products[j].Name })
+		for i := range section.Products {
+			section.Products[i].Categories = nil
+		}
+		sections = append(sections, section)
+	}
+	return sections, nil
+}
+
+// productWithLinks fills in the image, variants, modifier groups, categories
+// and tags of product.
+func (s *memoryStore) productWithLinks(product models.Product) models.Product {
+	product.Variants, product.ModifierGroups = s.productOptions(product.ID)
+	product.Categories = s.sortedCategories(s.productCategories[product.ID])
+	product.Tags = s.sortedTags(s.productTags[product.ID])
+	product.Image = nil
+	if image, ok := s.productImages[product.ID]; ok {
+		product.Image = &image
+	}
+	return product
+}
+
+func (s *memoryStore) sortedCategories(ids []uint) []models.Category {
+	categories := make([]models.Category, 0, len(ids))
+	for _, id := range ids {
+		categories = append(categories, s.categories[id])
+	}
+	sort.Slice(categories, func(i, j int) bool {
+		if categories[i].Position != categories[j].Position {
+			return categories[i].Position < categories[j].Position
+		}
+		return categories[i].Name < categories[j].Name
+	})
+	return categories
+}
+
+func (s *memoryStore) sortedTags(ids []uint) []models.Tag {
+	tags := make([]models.Tag, 0, len(ids))
+	for _, id := range ids {
+		tags = append(tags, s.tags[id])
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Name < tags[j].Name })
+	return tags
+}
+
+// linkIDs checks that every id is a key of m and returns them without
+// duplicates.
+func linkIDs[V any](m map[uint]V, ids []uint, unknown error) ([]uint, error) {
+	unique := []uint{}
+	for _, id := range ids {
+		if _, ok := m[id]; !ok {
+			return nil, unknown
+		}
+		if !containsID(unique, id) {
+			unique = append(unique, id)
+		}
+	}
+	return unique, nil
+}
+
+func without(ids []uint, id uint) []uint {
+	kept := []uint{}
+	for _, other := range ids {
+		if other != id {
+			kept = append(kept, other)
+		}
+	}
+	return kept
+}
+
+func containsID(ids []uint, id uint) bool {
+	for _, other := range ids {
+		if other == id {
+			return true
+		}
+	}
+	return false
+}
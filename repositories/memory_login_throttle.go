@@ -0,0 +1,51 @@
+package repositories
+
+import (
+	"go_final/models"
+	"time"
+)
+
+type memoryLoginThrottleRepository struct {
+	store *memoryStore
+}
+
+func (r *memoryLoginThrottleRepository) GetLoginThrottle(subject string) (models.LoginThrottle, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	if throttle, ok := r.store.loginThrottles[subject]; ok {
+		return throttle, nil
+	}
+	return models.LoginThrottle{Subject: subject}, nil
+}
+
+func (r *memoryLoginThrottleRepository) RecordLoginFailure(subject string, at time.Time) (models.LoginThrottle, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	throttle := r.store.loginThrottles[subject]
+	throttle.Subject = subject
+	throttle.Failures++
+	throttle.LastFailureAt = at
+	r.store.loginThrottles[subject] = throttle
+	return throttle, nil
+}
+
+func (r *memoryLoginThrottleRepository) LockLogin(subject string, until time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if throttle, ok := r.store.loginThrottles[subject]; ok {
+		throttle.LockedUntil = &until
+		r.store.loginThrottles[subject] = throttle
+	}
+	return nil
+}
+
+func (r *memoryLoginThrottleRepository) ResetLoginThrottle(subject string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	delete(r.store.loginThrottles, subject)
+	return nil
+}
@@ -0,0 +1,113 @@
+package repositories
+
+import (
+	"go_final/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type memoryMFARepository struct {
+	store *memoryStore
+}
+
+func (r *memoryMFARepository) GetMFA(userID uint) (models.UserMFA, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	if mfa, ok := r.store.userMFA[userID]; ok {
+		return mfa, nil
+	}
+	return models.UserMFA{UserID: userID}, nil
+}
+
+func (r *memoryMFARepository) SaveTOTPSecret(userID uint, secret string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := time.Now()
+	mfa, ok := r.store.userMFA[userID]
+	if !ok {
+		mfa = models.UserMFA{UserID: userID, CreatedAt: now}
+	}
+	mfa.TOTPSecret = secret
+	mfa.EnabledAt = nil
+	mfa.LastStep = 0
+	mfa.UpdatedAt = now
+	r.store.userMFA[userID] = mfa
+	return nil
+}
+
+func (r *memoryMFARepository) EnableTOTP(userID uint, step int64, codeHashes []string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	mfa, ok := r.store.userMFA[userID]
+	if !ok || mfa.EnabledAt != nil {
+		return gorm.ErrRecordNotFound
+	}
+	now := time.Now()
+	mfa.EnabledAt = &now
+	mfa.LastStep = step
+	r.store.userMFA[userID] = mfa
+	r.replaceRecoveryCodes(userID, codeHashes)
+	return nil
+}
+
+func (r *memoryMFARepository) DisableTOTP(userID uint) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	delete(r.store.userMFA, userID)
+	r.replaceRecoveryCodes(userID, nil)
+	return nil
+}
+
+func (r *memoryMFARepository) UseTOTPStep(userID uint, step int64) (bool, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	mfa, ok := r.store.userMFA[userID]
+	if !ok || mfa.EnabledAt == nil || mfa.LastStep >= step {
+		return false, nil
+	}
+	mfa.LastStep = step
+	r.store.userMFA[userID] = mfa
+	return true, nil
+}
+
+func (r *memoryMFARepository) UseRecoveryCode(userID uint, codeHash string) (bool, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for id, code := range r.store.recoveryCodes {
+		if code.UserID == userID && code.CodeHash == codeHash && code.UsedAt == nil {
+			now := time.Now()
+			code.UsedAt = &now
+			r.store.recoveryCodes[id] = code
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *memoryMFARepository) ReplaceRecoveryCodes(userID uint, codeHashes []string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	r.replaceRecoveryCodes(userID, codeHashes)
+	return nil
+}
+
+// replaceRecoveryCodes expects the caller to hold the write lock.
+func (r *memoryMFARepository) replaceRecoveryCodes(userID uint, codeHashes []string) {
+	for id, code := range r.store.recoveryCodes {
+		if code.UserID == userID {
+			delete(r.store.recoveryCodes, id)
+		}
+	}
+	for _, hash := range codeHashes {
+		id := r.store.nextID("recovery_codes")
+		r.store.recoveryCodes[id] = models.RecoveryCode{ID: id, UserID: userID, CodeHash: hash, CreatedAt: time.Now()}
+	}
+}
@@ -0,0 +1,153 @@
+package repositories
+
+import (
+	"go_final/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type memoryOrderRepository struct {
+	store *memoryStore
+}
+
+func (r *memoryOrderRepository) GetOrders(userID uint) ([]models.Order, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	orders := []models.Order{}
+	for _, id := range sortedIDs(r.store.orders) {
+		if order := r.store.orders[id]; order.UserID == userID {
+			orders = append(orders, order)
+		}
+	}
+	return orders, nil
+}
+
+func (r *memoryOrderRepository) ListOrders(query ListQuery) (Page[models.Order], error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	orders := make([]models.Order, 0, len(r.store.orders))
+	for _, order := range r.store.orders {
+		orders = append(orders, order)
+	}
+	return paginateSlice(orders, OrderListSpec, query), nil
+}
+
+func (r *memoryOrderRepository) GetOrderByID(orderID uint) (models.Order, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	order, ok := r.store.orders[orderID]
+	if !ok {
+		return models.Order{}, gorm.ErrRecordNotFound
+	}
+	return order, nil
+}
+
+func (r *memoryOrderRepository) GetOrderItems(orderID uint) ([]models.OrderItems, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	orderItems := []models.OrderItems{}
+	for _, id := range sortedIDs(r.store.orderItems) {
+		item := r.store.orderItems[id]
+		if item.OrderID != orderID {
+			continue
+		}
+		item.Order = r.store.orders[item.OrderID]
+		item.Product = r.store.products[item.ProductID]
+		orderItems = append(orderItems, item)
+	}
+	return orderItems, nil
+}
+
+func (r *memoryOrderRepository) UpdateOrderStatus(orderID uint, current, next models.OrderStatus) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	order, ok := r.store.orders[orderID]
+	if !ok || order.OrderStatus != current {
+		return ErrOrderStatusChanged
+	}
+	order.OrderStatus = next
+	order.UpdatedAt = time.Now()
+	r.store.orders[orderID] = order
+	if returnsStock(current, next) {
+		for _, id := range sortedIDs(r.store.orderItems) {
+			if item := r.store.orderItems[id]; item.OrderID == orderID {
+				r.store.adjustInventory(item)
+			}
+		}
+	}
+	return nil
+}
+
+func (r *memoryOrderRepository) SetOrderDiscount(orderID uint, percent int) (models.Order, error) {
+	var order models.Order
+	err := r.store.transaction(func() error {
+		existing, ok := r.store.orders[orderID]
+		if !ok {
+			return gorm.ErrRecordNotFound
+		}
+		if existing.OrderStatus == models.CONFIRMED || existing.OrderStatus == models.CANCELED {
+			return ErrOrderClosed
+		}
+		existing.DiscountPercent = percent
+		existing.UpdatedAt = time.Now()
+		r.store.orders[orderID] = existing
+		if err := r.store.updateTotals(orderID); err != nil {
+			return err
+		}
+		order = r.store.orders[orderID]
+		return nil
+	})
+	return order, err
+}
+
+// newModifiers gives the modifiers of an order item ids.
+func (s *memoryStore) newModifiers(orderItemID uint, modifiers []models.OrderItemModifier) []models.OrderItemModifier {
+	for i := range modifiers {
+		modifiers[i].ID = s.nextID("order_item_modifiers")
+		modifiers[i].OrderItemID = orderItemID
+	}
+	return modifiers
+}
+
+// adjustInventory returns the items of an order item to stock, like the
+// gorm repository.
+func (s *memoryStore) adjustInventory(item models.OrderItems) {
+	if item.VariantID != nil {
+		if variant, ok := s.variants[*item.VariantID]; ok {
+			variant.Quantity += item.Quantity
+			s.variants[variant.ID] = variant
+		}
+		return
+	}
+	if item.VariantName != "" {
+		return
+	}
+	if product, ok := s.products[item.ProductID]; ok {
+		product.Quantity += item.Quantity
+		s.products[item.ProductID] = product
+	}
+}
+
+func (s *memoryStore) updateTotals(orderID uint) error {
+	order, ok := s.orders[orderID]
+	if !ok {
+		return nil
+	}
+	items := []models.OrderItems{}
+	for _, item := range s.orderItems {
+		if item.OrderID == orderID {
+			items = append(items, item)
+		}
+	}
+	if err := setTotals(&order, items); err != nil {
+		return err
+	}
+	s.orders[orderID] = order
+	return nil
+}
@@ -0,0 +1,184 @@
+package repositories
+
+import (
+	"errors"
+	"go_final/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var ErrProductNameTaken = errors.New("product with this name already exists")
+
+type memoryProductRepository struct {
+	store *memoryStore
+}
+
+func (r *memoryProductRepository) nameTaken(name string, exceptID uint) bool {
+	for _, product := range r.store.products {
+		if product.Name == name && product.ID != exceptID {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *memoryProductRepository) Getproduct(id int) (models.Product, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	product, ok := r.store.products[uint(id)]
+	if !ok {
+		return models.Product{}, gorm.ErrRecordNotFound
+	}
+	return r.store.productWithLinks(product), nil
+}
+
+func (r *memoryProductRepository) GetAllproduct(query ListQuery) (Page[models.Product], error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	products := make([]models.Product, 0, len(r.store.products))
+	for _, product := range r.store.products {
+		products = append(products, r.store.productWithLinks(product))
+	}
+	return paginateSlice(products, ProductListSpec, query), nil
+}
+
+func (r *memoryProductRepository) AddProduct(product models.Product) (models.Product, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if r.nameTaken(product.Name, 0) {
+		return product, ErrProductNameTaken
+	}
+	categoryIDs, tagIDs, err := r.linkIDs(product)
+	if err != nil {
+		return product, err
+	}
+	product.Model = r.store.newModel("products")
+	product.CategoryIDs, product.TagIDs = nil, nil
+	product.Categories, product.Tags, product.Image = nil, nil, nil
+	product.Variants, product.ModifierGroups = nil, nil
+	r.store.products[product.ID] = product
+	r.setLinks(product.ID, categoryIDs, tagIDs)
+	return r.store.productWithLinks(product), nil
+}
+
+// linkIDs validates the categories and tags product is to be linked to, nil
+// leaves the current links alone.
+func (r *memoryProductRepository) linkIDs(product models.Product) (categoryIDs, tagIDs []uint, err error) {
+	if product.CategoryIDs != nil {
+		if categoryIDs, err = linkIDs(r.store.categories, product.CategoryIDs, ErrUnknownCategory); err != nil {
+			return nil, nil, err
+		}
+	}
+	if product.TagIDs != nil {
+		if tagIDs, err = linkIDs(r.store.tags, product.TagIDs, ErrUnknownTag); err != nil {
+			return nil, nil, err
+		}
+	}
+	return categoryIDs, tagIDs, nil
+}
+
+func (r *memoryProductRepository) setLinks(productID uint, categoryIDs, tagIDs []uint) {
+	if categoryIDs != nil {
+		r.store.productCategories[productID] = categoryIDs
+	}
+	if tagIDs != nil {
+		r.store.productTags[productID] = tagIDs
+	}
+}
+
+func (r *memoryProductRepository) UpdateProduct(product models.Product) (models.Product, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	existing, ok := r.store.products[product.ID]
+	if !ok {
+		return product, gorm.ErrRecordNotFound
+	}
+	if product.Name != "" && r.nameTaken(product.Name, product.ID) {
+		return product, ErrProductNameTaken
+	}
+	categoryIDs, tagIDs, err := r.linkIDs(product)
+	if err != nil {
+		return product, err
+	}
+
+	if product.Name != "" {
+		existing.Name = product.Name
+	}
+	if product.Quantity != 0 {
+		existing.Quantity = product.Quantity
+	}
+	if product.Description != "" {
+		existing.Description = product.Description
+	}
+	if !product.Price.IsZero() {
+		existing.Price = product.Price
+	}
+	existing.UpdatedAt = time.Now()
+	r.store.products[product.ID] = existing
+	r.setLinks(product.ID, categoryIDs, tagIDs)
+	return r.store.productWithLinks(existing), nil
+}
+
+func (r *memoryProductRepository) DeleteProduct(product models.Product) (models.Product, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	existing, ok := r.store.products[product.ID]
+	if !ok {
+		return product, gorm.ErrRecordNotFound
+	}
+	existing = r.store.productWithLinks(existing)
+	delete(r.store.products, product.ID)
+	delete(r.store.productCategories, product.ID)
+	delete(r.store.productTags, product.ID)
+	delete(r.store.productImages, product.ID)
+	r.store.deleteProductOptions(product.ID)
+
+	// order_items.product_id is ON DELETE SET NULL, cart_items.product_id
+	// ON DELETE CASCADE
+	for id, item := range r.store.orderItems {
+		if item.ProductID == product.ID {
+			item.ProductID = 0
+			r.store.orderItems[id] = item
+		}
+	}
+	for id, item := range r.store.cartItems {
+		if item.ProductID == product.ID {
+			delete(r.store.cartItems, id)
+		}
+	}
+	return existing, nil
+}
+
+func (r *memoryProductRepository) SetProductImage(image models.ProductImage) (*models.ProductImage, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.products[image.ProductID]; !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	var previous *models.ProductImage
+	if existing, ok := r.store.productImages[image.ProductID]; ok {
+		previous = &existing
+	}
+	image.CreatedAt = time.Now()
+	r.store.productImages[image.ProductID] = image
+	return previous, nil
+}
+
+func (r *memoryProductRepository) DeleteProductImage(productID uint) (models.ProductImage, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	image, ok := r.store.productImages[productID]
+	if !ok {
+		return image, gorm.ErrRecordNotFound
+	}
+	delete(r.store.productImages, productID)
+	return image, nil
+}
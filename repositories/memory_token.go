@@ -0,0 +1,136 @@
+package repositories
+
+import (
+	"go_final/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type memoryTokenRepository struct {
+	store *memoryStore
+}
+
+func (r *memoryTokenRepository) CreateRefreshToken(token models.RefreshToken) (models.RefreshToken, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	token.Model = r.store.newModel("refresh_tokens")
+	r.store.refreshTokens[token.ID] = token
+	return token, nil
+}
+
+func (r *memoryTokenRepository) GetRefreshToken(hash string) (models.RefreshToken, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, token := range r.store.refreshTokens {
+		if token.TokenHash == hash {
+			return token, nil
+		}
+	}
+	return models.RefreshToken{}, gorm.ErrRecordNotFound
+}
+
+func (r *memoryTokenRepository) RotateRefreshToken(oldID uint, next models.RefreshToken) (models.RefreshToken, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	old, ok := r.store.refreshTokens[oldID]
+	if !ok || old.RevokedAt != nil {
+		return next, ErrTokenRevoked
+	}
+	now := time.Now()
+	old.RevokedAt = &now
+	r.store.refreshTokens[oldID] = old
+
+	next.Model = r.store.newModel("refresh_tokens")
+	r.store.refreshTokens[next.ID] = next
+	return next, nil
+}
+
+func (r *memoryTokenRepository) revokeRefreshTokens(match func(models.RefreshToken) bool) {
+	now := time.Now()
+	for id, token := range r.store.refreshTokens {
+		if token.RevokedAt == nil && match(token) {
+			token.RevokedAt = &now
+			r.store.refreshTokens[id] = token
+		}
+	}
+}
+
+func (r *memoryTokenRepository) RevokeRefreshTokenFamily(familyID string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	r.revokeRefreshTokens(func(token models.RefreshToken) bool { return token.FamilyID == familyID })
+	return nil
+}
+
+func (r *memoryTokenRepository) RevokeAccessToken(jti string, expiresAt time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := time.Now()
+	for id, revoked := range r.store.revokedTokens {
+		if revoked.ExpiresAt.Before(now) {
+			delete(r.store.revokedTokens, id)
+		}
+	}
+	r.store.revokedTokens[jti] = models.RevokedToken{JTI: jti, ExpiresAt: expiresAt, CreatedAt: now}
+	return nil
+}
+
+func (r *memoryTokenRepository) RevokeUserTokens(userID uint) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	r.store.tokenCutoffs[userID] = models.UserTokenCutoff{UserID: userID, RevokedBefore: time.Now()}
+	r.revokeRefreshTokens(func(token models.RefreshToken) bool { return token.UserID == userID })
+	return nil
+}
+
+func (r *memoryTokenRepository) IsAccessTokenRevoked(jti string, userID uint, issuedAt time.Time) (bool, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	if _, ok := r.store.revokedTokens[jti]; ok {
+		return true, nil
+	}
+	return isBeforeCutoff(issuedAt, r.store.tokenCutoffs[userID].RevokedBefore), nil
+}
+
+func (r *memoryTokenRepository) CreateUserToken(token models.UserToken) (models.UserToken, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := time.Now()
+	for id, existing := range r.store.userTokens {
+		if existing.UserID == token.UserID && existing.Purpose == token.Purpose && existing.UsedAt == nil {
+			existing.UsedAt = &now
+			r.store.userTokens[id] = existing
+		}
+	}
+	token.Model = r.store.newModel("user_tokens")
+	r.store.userTokens[token.ID] = token
+	return token, nil
+}
+
+func (r *memoryTokenRepository) ConsumeUserToken(purpose models.UserTokenPurpose, hash string) (models.UserToken, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := time.Now()
+	for id, token := range r.store.userTokens {
+		if token.TokenHash != hash || token.Purpose != purpose {
+			continue
+		}
+		if token.UsedAt != nil || now.After(token.ExpiresAt) {
+			return models.UserToken{}, ErrUserTokenInvalid
+		}
+		token.UsedAt = &now
+		r.store.userTokens[id] = token
+		return token, nil
+	}
+	return models.UserToken{}, ErrUserTokenInvalid
+}
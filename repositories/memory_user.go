@@ -0,0 +1,219 @@
+package repositories
+
+import (
+	"errors"
+	"go_final/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var ErrEmailTaken = errors.New("user with this email already exists")
+
+type memoryUserRepository struct {
+	store *memoryStore
+}
+
+func toAPIUser(user models.User) models.APIUser {
+	return models.APIUser{
+		ID:              user.ID,
+		Name:            user.Name,
+		Email:           user.Email,
+		Role:            user.Role,
+		EmailVerifiedAt: user.EmailVerifiedAt,
+		CreatedAt:       user.CreatedAt,
+		AnonymizedAt:    user.AnonymizedAt,
+	}
+}
+
+// toDeletedAPIUser is toAPIUser for users in deletedUsers.
+func toDeletedAPIUser(user models.User) models.APIUser {
+	deleted := toAPIUser(user)
+	deleted.DeletedAt = &user.DeletedAt.Time
+	return deleted
+}
+
+// emailTaken includes deleted users, like the unique index of the users
+// table.
+func (r *memoryUserRepository) emailTaken(email string, exceptID uint) bool {
+	for _, users := range []map[uint]models.User{r.store.users, r.store.deletedUsers} {
+		for _, user := range users {
+			if user.Email == email && user.ID != exceptID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (r *memoryUserRepository) GetUser(id int) (models.APIUser, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	user, ok := r.store.users[uint(id)]
+	if !ok {
+		return models.APIUser{}, gorm.ErrRecordNotFound
+	}
+	return toAPIUser(user), nil
+}
+
+func (r *memoryUserRepository) GetByEmail(email string) (models.User, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, id := range sortedIDs(r.store.users) {
+		if user := r.store.users[id]; user.Email == email {
+			return user, nil
+		}
+	}
+	return models.User{}, gorm.ErrRecordNotFound
+}
+
+func (r *memoryUserRepository) GetByID(id uint) (models.User, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	user, ok := r.store.users[id]
+	if !ok {
+		return models.User{}, gorm.ErrRecordNotFound
+	}
+	return user, nil
+}
+
+func (r *memoryUserRepository) GetAllUsers(query ListQuery) (Page[models.APIUser], error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	users := make([]models.APIUser, 0, len(r.store.users))
+	for _, user := range r.store.users {
+		users = append(users, toAPIUser(user))
+	}
+	return paginateSlice(users, UserListSpec, query), nil
+}
+
+func (r *memoryUserRepository) CreateUser(user models.User) (models.User, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if r.emailTaken(user.Email, 0) {
+		return user, ErrEmailTaken
+	}
+	user.Model = r.store.newModel("users")
+	r.store.users[user.ID] = user
+	return user, nil
+}
+
+func (r *memoryUserRepository) UpdateUser(user models.User) (models.User, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	existing, ok := r.store.users[user.ID]
+	if !ok {
+		return user, gorm.ErrRecordNotFound
+	}
+	if user.Email != "" && r.emailTaken(user.Email, user.ID) {
+		return user, ErrEmailTaken
+	}
+
+	if user.Name != "" {
+		existing.Name = user.Name
+	}
+	if user.Email != "" {
+		existing.Email = user.Email
+	}
+	if user.Password != "" {
+		existing.Password = user.Password
+	}
+	if user.Role != "" {
+		existing.Role = user.Role
+	}
+	if user.EmailVerifiedAt != nil {
+		existing.EmailVerifiedAt = user.EmailVerifiedAt
+	}
+	existing.UpdatedAt = time.Now()
+	r.store.users[user.ID] = existing
+	return existing, nil
+}
+
+func (r *memoryUserRepository) DeleteUser(user models.User) (models.User, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	existing, ok := r.store.users[user.ID]
+	if !ok {
+		return user, gorm.ErrRecordNotFound
+	}
+	delete(r.store.users, user.ID)
+	existing.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+	r.store.deletedUsers[user.ID] = existing
+	return existing, nil
+}
+
+func (r *memoryUserRepository) GetDeletedUsers() ([]models.APIUser, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	users := make([]models.APIUser, 0, len(r.store.deletedUsers))
+	for _, id := range sortedIDs(r.store.deletedUsers) {
+		users = append(users, toDeletedAPIUser(r.store.deletedUsers[id]))
+	}
+	return users, nil
+}
+
+func (r *memoryUserRepository) RestoreUser(id uint) (models.APIUser, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	user, ok := r.store.deletedUsers[id]
+	if !ok {
+		return models.APIUser{}, gorm.ErrRecordNotFound
+	}
+	if user.AnonymizedAt != nil {
+		return models.APIUser{}, ErrUserAnonymized
+	}
+	delete(r.store.deletedUsers, id)
+	user.DeletedAt = gorm.DeletedAt{}
+	r.store.users[id] = user
+	return toAPIUser(user), nil
+}
+
+func (r *memoryUserRepository) AnonymizeUser(id uint) (models.APIUser, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := time.Now()
+	user, ok := r.store.deletedUsers[id]
+	if !ok {
+		if user, ok = r.store.users[id]; !ok {
+			return models.APIUser{}, gorm.ErrRecordNotFound
+		}
+		delete(r.store.users, id)
+		user.DeletedAt = gorm.DeletedAt{Time: now, Valid: true}
+	}
+	user.Name = anonymizedName
+	user.Email = anonymizedEmail(id)
+	user.Password = ""
+	user.EmailVerifiedAt = nil
+	user.AnonymizedAt = &now
+	user.UpdatedAt = now
+	r.store.deletedUsers[id] = user
+
+	for tokenID, token := range r.store.userTokens {
+		if token.UserID == id {
+			delete(r.store.userTokens, tokenID)
+		}
+	}
+	for tokenID, token := range r.store.refreshTokens {
+		if token.UserID == id {
+			delete(r.store.refreshTokens, tokenID)
+		}
+	}
+	for codeID, code := range r.store.recoveryCodes {
+		if code.UserID == id {
+			delete(r.store.recoveryCodes, codeID)
+		}
+	}
+	delete(r.store.userMFA, id)
+	r.store.clearCart(id)
+	return toDeletedAPIUser(user), nil
+}
@@ -0,0 +1,175 @@
+package repositories
+
+import (
+	"go_final/models"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func (r *memoryProductRepository) AddVariant(variant models.ProductVariant) (models.ProductVariant, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.products[variant.ProductID]; !ok {
+		return variant, gorm.ErrRecordNotFound
+	}
+	variant.Model = r.store.newModel("product_variants")
+	r.store.variants[variant.ID] = variant
+	return variant, nil
+}
+
+func (r *memoryProductRepository) UpdateVariant(variant models.ProductVariant) (models.ProductVariant, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	existing, ok := r.store.variants[variant.ID]
+	if !ok || existing.ProductID != variant.ProductID {
+		return variant, gorm.ErrRecordNotFound
+	}
+	variant.CreatedAt = existing.CreatedAt
+	variant.UpdatedAt = time.Now()
+	r.store.variants[variant.ID] = variant
+	return variant, nil
+}
+
+func (r *memoryProductRepository) DeleteVariant(productID, variantID uint) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if variant, ok := r.store.variants[variantID]; !ok || variant.ProductID != productID {
+		return gorm.ErrRecordNotFound
+	}
+	r.store.deleteVariant(variantID)
+	return nil
+}
+
+// deleteVariant removes a variant, order_items.variant_id is ON DELETE SET
+// NULL and cart_items.variant_id ON DELETE CASCADE.
+func (s *memoryStore) deleteVariant(variantID uint) {
+	delete(s.variants, variantID)
+	for id, item := range s.orderItems {
+		if item.VariantID != nil && *item.VariantID == variantID {
+			item.VariantID = nil
+			s.orderItems[id] = item
+		}
+	}
+	for id, item := range s.cartItems {
+		if item.VariantID != nil && *item.VariantID == variantID {
+			delete(s.cartItems, id)
+		}
+	}
+}
+
+func (r *memoryProductRepository) AddModifierGroup(group models.ModifierGroup) (models.ModifierGroup, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.products[group.ProductID]; !ok {
+		return group, gorm.ErrRecordNotFound
+	}
+	group.Model = r.store.newModel("modifier_groups")
+	r.store.setOptions(&group)
+	r.store.modifierGroups[group.ID] = group
+	return group, nil
+}
+
+func (r *memoryProductRepository) UpdateModifierGroup(group models.ModifierGroup) (models.ModifierGroup, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	existing, ok := r.store.modifierGroups[group.ID]
+	if !ok || existing.ProductID != group.ProductID {
+		return group, gorm.ErrRecordNotFound
+	}
+	r.store.forgetOptions(existing)
+	group.CreatedAt = existing.CreatedAt
+	group.UpdatedAt = time.Now()
+	r.store.setOptions(&group)
+	r.store.modifierGroups[group.ID] = group
+	return group, nil
+}
+
+func (r *memoryProductRepository) DeleteModifierGroup(productID, groupID uint) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	group, ok := r.store.modifierGroups[groupID]
+	if !ok || group.ProductID != productID {
+		return gorm.ErrRecordNotFound
+	}
+	r.store.forgetOptions(group)
+	delete(r.store.modifierGroups, groupID)
+	return nil
+}
+
+// setOptions gives the options of group new ids.
+func (s *memoryStore) setOptions(group *models.ModifierGroup) {
+	options := make([]models.ModifierOption, len(group.Options))
+	for i, option := range group.Options {
+		option.Model = s.newModel("modifier_options")
+		option.GroupID = group.ID
+		options[i] = option
+	}
+	sort.SliceStable(options, func(i, j int) bool { return options[i].Position < options[j].Position })
+	group.Options = options
+}
+
+// forgetOptions unlinks order items from the options of group,
+// order_item_modifiers.modifier_option_id is ON DELETE SET NULL. Cart items
+// keep them, cart_item_modifiers.modifier_option_id has no foreign key, so
+// their lines report the option as unknown.
+func (s *memoryStore) forgetOptions(group models.ModifierGroup) {
+	for id, item := range s.orderItems {
+		changed := false
+		modifiers := make([]models.OrderItemModifier, len(item.Modifiers))
+		for i, modifier := range item.Modifiers {
+			for _, option := range group.Options {
+				if modifier.ModifierOptionID != nil && *modifier.ModifierOptionID == option.ID {
+					modifier.ModifierOptionID = nil
+					changed = true
+				}
+			}
+			modifiers[i] = modifier
+		}
+		if changed {
+			item.Modifiers = modifiers
+			s.orderItems[id] = item
+		}
+	}
+}
+
+// productOptions returns the variants and modifier groups of a product in
+// position order.
+func (s *memoryStore) productOptions(productID uint) ([]models.ProductVariant, []models.ModifierGroup) {
+	variants := []models.ProductVariant{}
+	for _, id := range sortedIDs(s.variants) {
+		if variant := s.variants[id]; variant.ProductID == productID {
+			variants = append(variants, variant)
+		}
+	}
+	sort.SliceStable(variants, func(i, j int) bool { return variants[i].Position < variants[j].Position })
+
+	groups := []models.ModifierGroup{}
+	for _, id := range sortedIDs(s.modifierGroups) {
+		if group := s.modifierGroups[id]; group.ProductID == productID {
+			groups = append(groups, group)
+		}
+	}
+	sort.SliceStable(groups, func(i, j int) bool { return groups[i].Position < groups[j].Position })
+	return variants, groups
+}
+
+// deleteProductOptions removes the variants and modifier groups of a
+// deleted product.
+func (s *memoryStore) deleteProductOptions(productID uint) {
+	variants, groups := s.productOptions(productID)
+	for _, variant := range variants {
+		s.deleteVariant(variant.ID)
+	}
+	for _, group := range groups {
+		s.forgetOptions(group)
+		delete(s.modifierGroups, group.ID)
+	}
+}
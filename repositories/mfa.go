@@ -0,0 +1,113 @@
+package repositories
+
+import (
+	"go_final/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type MFARepository interface {
+	GetMFA(uint) (models.UserMFA, error)
+	SaveTOTPSecret(uint, string) error
+	EnableTOTP(uint, int64, []string) error
+	DisableTOTP(uint) error
+	UseTOTPStep(uint, int64) (bool, error)
+	UseRecoveryCode(uint, string) (bool, error)
+	ReplaceRecoveryCodes(uint, []string) error
+}
+
+type mfaRepository struct {
+	connection *gorm.DB
+}
+
+func NewMFARepository(db *gorm.DB) MFARepository {
+	return &mfaRepository{
+		connection: db,
+	}
+}
+
+// GetMFA returns an empty record for users who never enrolled.
+func (db *mfaRepository) GetMFA(userID uint) (models.UserMFA, error) {
+	mfa := models.UserMFA{UserID: userID}
+	err := db.connection.Where("user_id = ?", userID).Limit(1).Find(&mfa).Error
+	return mfa, err
+}
+
+// SaveTOTPSecret stores a secret that is not enabled yet, replacing any
+// earlier enrollment that was never confirmed.
+func (db *mfaRepository) SaveTOTPSecret(userID uint, secret string) error {
+	mfa := models.UserMFA{UserID: userID, TOTPSecret: secret}
+	return db.connection.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"totp_secret": secret,
+			"enabled_at":  nil,
+			"last_step":   0,
+			"updated_at":  time.Now(),
+		}),
+	}).Create(&mfa).Error
+}
+
+// EnableTOTP enables the pending secret, records step as used and stores the
+// first recovery codes.
+func (db *mfaRepository) EnableTOTP(userID uint, step int64, codeHashes []string) error {
+	return db.connection.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.UserMFA{}).
+			Where("user_id = ? AND enabled_at IS NULL", userID).
+			Updates(map[string]interface{}{"enabled_at": time.Now(), "last_step": step})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return replaceRecoveryCodes(tx, userID, codeHashes)
+	})
+}
+
+func (db *mfaRepository) DisableTOTP(userID uint) error {
+	return db.connection.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&models.UserMFA{}).Error
+	})
+}
+
+// UseTOTPStep records step as used. It reports false if a code of that step
+// or a later one was accepted before.
+func (db *mfaRepository) UseTOTPStep(userID uint, step int64) (bool, error) {
+	result := db.connection.Model(&models.UserMFA{}).
+		Where("user_id = ? AND enabled_at IS NOT NULL AND last_step < ?", userID, step).
+		Update("last_step", step)
+	return result.RowsAffected == 1, result.Error
+}
+
+func (db *mfaRepository) UseRecoveryCode(userID uint, codeHash string) (bool, error) {
+	result := db.connection.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Update("used_at", time.Now())
+	return result.RowsAffected == 1, result.Error
+}
+
+func (db *mfaRepository) ReplaceRecoveryCodes(userID uint, codeHashes []string) error {
+	return db.connection.Transaction(func(tx *gorm.DB) error {
+		return replaceRecoveryCodes(tx, userID, codeHashes)
+	})
+}
+
+func replaceRecoveryCodes(tx *gorm.DB, userID uint, codeHashes []string) error {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return err
+	}
+	codes := make([]models.RecoveryCode, len(codeHashes))
+	for i, hash := range codeHashes {
+		codes[i] = models.RecoveryCode{UserID: userID, CodeHash: hash}
+	}
+	if len(codes) == 0 {
+		return nil
+	}
+	return tx.Create(&codes).Error
+}
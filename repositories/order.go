@@ -2,30 +2,48 @@ package repositories
 
 import (
 	"errors"
-	"fmt"
 	"go_final/models"
+	"go_final/money"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
 
+// OrderListSpec are the fields orders can be sorted and filtered by.
+var OrderListSpec = ListSpec[models.Order]{Fields: map[string]Field[models.Order]{
+	"id":         {Column: "id", Kind: IntField, Value: func(o models.Order) any { return o.ID }},
+	"status":     {Column: "order_status", Kind: StringField, Value: func(o models.Order) any { return string(o.OrderStatus) }},
+	"user_id":    {Column: "user_id", Kind: IntField, Value: func(o models.Order) any { return o.UserID }},
+	"total":      {Column: "total_amount", Kind: IntField, Value: func(o models.Order) any { return o.Total.Amount }},
+	"created_at": {Column: "created_at", Kind: TimeField, Value: func(o models.Order) any { return o.CreatedAt }},
+}}
+
+var (
+	ErrOrderStatusChanged = errors.New("order status has changed in the meantime")
+	ErrOrderClosed        = errors.New("order is confirmed or canceled")
+)
+
 type OrderRepository interface {
 	GetOrders(uint) ([]models.Order, error)
+	ListOrders(ListQuery) (Page[models.Order], error)
 	GetOrderByID(uint) (models.Order, error)
 	GetOrderItems(uint) ([]models.OrderItems, error)
-	OrderProducts(uint, []models.CartItemRequest) error
-	UpdateOrder(uint, []models.CartItemRequest) error
-	UpdateOrderStatus(uint, string) error
-	DeleteOrder(uint) error
-	DeleteOrderItem(uint, uint) error
+	// UpdateOrderStatus moves an order from current to next. It fails with
+	// ErrOrderStatusChanged if the order is no longer in current. The items
+	// of orders canceled before they are cooked go back to stock.
+	UpdateOrderStatus(orderID uint, current, next models.OrderStatus) error
+	// SetOrderDiscount recomputes the totals with the VAT the order was
+	// placed with. It fails with ErrOrderClosed once the order is confirmed
+	// or canceled.
+	SetOrderDiscount(orderID uint, percent int) (models.Order, error)
 }
 
 type orderRepository struct {
 	connection *gorm.DB
 }
 
-func NewOrderRepository() OrderRepository {
+func NewOrderRepository(db *gorm.DB) OrderRepository {
 	return &orderRepository{
-		connection: DB(),
+		connection: db,
 	}
 }
 
@@ -37,6 +55,10 @@ func (db *orderRepository) GetOrders(userID uint) ([]models.Order, error) {
 	return orders, nil
 }
 
+func (db *orderRepository) ListOrders(query ListQuery) (Page[models.Order], error) {
+	return paginate(db.connection.Model(&models.Order{}), OrderListSpec, query)
+}
+
 func (db *orderRepository) GetOrderByID(orderID uint) (models.Order, error) {
 	var order models.Order
 	if err := db.connection.First(&order, orderID).Error; err != nil {
@@ -56,188 +78,134 @@ func (db *orderRepository) GetOrderItems(orderID uint) ([]models.OrderItems, err
 	return orderItems, nil
 }
 
-func addNewOrderItem(tx *gorm.DB, orderID uint, item models.CartItemRequest) error {
-	var product models.Product
-	if err := tx.First(&product, item.ProductID).Error; err != nil {
-		return err
-	}
-
-	if product.Quantity < item.Quantity {
-		return errors.New("not enough stock available for: " + product.Name)
-	}
-
-	newQuantity := product.Quantity - item.Quantity
-	if err := tx.Model(&models.Product{}).Where("id = ?", item.ProductID).Update("quantity", newQuantity).Error; err != nil {
-		return err
-	}
-
-	orderItem := models.OrderItems{
-		OrderID:   orderID,
-		ProductID: item.ProductID,
-		Quantity:  item.Quantity,
-		Price:     product.Price,
-	}
-
-	if err := tx.Create(&orderItem).Error; err != nil {
-		return err
-	}
-
-	return nil
-}
-
-func (db *orderRepository) OrderProducts(userID uint, request []models.CartItemRequest) error {
+func (db *orderRepository) UpdateOrderStatus(orderID uint, current, next models.OrderStatus) error {
 	return db.connection.Transaction(func(tx *gorm.DB) error {
-		var newOrder models.Order
-		result := tx.Where("user_id = ? AND (order_status = ? OR order_status = ?)", userID, models.PENDING, models.ACCEPTED).FirstOrCreate(&newOrder, models.Order{UserID: userID, OrderStatus: models.PENDING})
+		result := tx.Model(&models.Order{}).
+			Where("id = ? AND order_status = ?", orderID, current).
+			Update("order_status", next)
 		if result.Error != nil {
 			return result.Error
 		}
-
-		for _, item := range request {
-			if err := addNewOrderItem(tx, newOrder.ID, item); err != nil {
-				return err
-			}
+		if result.RowsAffected == 0 {
+			return ErrOrderStatusChanged
 		}
-
-		return nil
-	})
-}
-
-func (db *orderRepository) UpdateOrder(userID uint, request []models.CartItemRequest) error {
-	return db.connection.Transaction(func(tx *gorm.DB) error {
-		var order models.Order
-		result := tx.Where("user_id = ? AND (order_status = ? OR order_status = ?)", userID, models.PENDING, models.ACCEPTED).First(&order)
-		if result.Error != nil {
-			return result.Error
+		if !returnsStock(current, next) {
+			return nil
 		}
-
-		// заказ до обновления
-		var existingItems []models.OrderItems
-		if err := tx.Where("order_id = ?", order.ID).Find(&existingItems).Error; err != nil {
+		var items []models.OrderItems
+		if err := tx.Where("order_id = ?", orderID).Find(&items).Error; err != nil {
 			return err
 		}
-
-		// мапим по айдишкам чтобы потом легче обращаться
-		existingMap := make(map[uint]models.OrderItems)
-		for _, item := range existingItems {
-			existingMap[item.ID] = item
-		}
-
-		// пробегаемся по каждому элементу апдейта заказа
-		for _, newItem := range request {
-			existingItem, exists := existingMap[newItem.OrderItemID]
-
-			// обновляем существующий элемент в заказе (т.е. этот продукт был до обновления
-			// или нужно добавить новый продукт в заказ)
-			if exists {
-				// если этот продукт нужно полностью заменить другим
-				if existingItem.ProductID != newItem.ProductID {
-					// проверяем наличие для нового продукта и уменьшаем количество в базе
-					if err := checkAndAdjustStock(tx, newItem.ProductID, -newItem.Quantity); err != nil {
-						return err
-					}
-					// возвращаем на склад старый товар и увеличиваем количество в базе без проверки
-					if err := adjustInventory(tx, existingItem.ProductID, existingItem.Quantity); err != nil {
-						return err
-					}
-					// если это тот же товар и юзер просто меняет его количество
-				} else if existingItem.Quantity != newItem.Quantity {
-					// меняем количество на складе
-					difference := newItem.Quantity - existingItem.Quantity
-					// -diff потому что когда юзер уменьшил количество товара мы должны вернуть эту разницу на склад
-					if err := checkAndAdjustStock(tx, newItem.ProductID, -difference); err != nil {
-						return err
-					}
-				}
-
-				// сохраняем изменения
-				existingItem.ProductID = newItem.ProductID // на случай если товар поменялся
-				existingItem.Quantity = newItem.Quantity
-				if err := tx.Save(&existingItem).Error; err != nil {
-					return err
-				}
-			} else {
-				// просто доваляем новый товар
-				if err := addNewOrderItem(tx, order.ID, newItem); err != nil {
-					return err
-				}
+		for _, item := range items {
+			if err := adjustInventory(tx, item); err != nil {
+				return err
 			}
 		}
-
 		return nil
 	})
 }
 
-func (db *orderRepository) UpdateOrderStatus(orderID uint, newStatus string) error {
-	return db.connection.Model(&models.Order{}).Where("id = ?", orderID).Update("order_status", newStatus).Error
+// returnsStock reports whether moving an order from current to next puts
+// its items back in stock. Food that was cooked or handed over is not taken
+// back.
+func returnsStock(current, next models.OrderStatus) bool {
+	return next == models.CANCELED && (current == models.PENDING || current == models.ACCEPTED)
 }
 
-func checkAndAdjustStock(tx *gorm.DB, productID uint, quantityChange int) error {
-	var product models.Product
-	if err := tx.First(&product, productID).Error; err != nil {
-		return err
+// adjustInventory returns the items of an order item to stock. Items whose
+// variant was deleted have nowhere to go back to.
+func adjustInventory(tx *gorm.DB, item models.OrderItems) error {
+	if item.VariantID != nil {
+		return tx.Model(&models.ProductVariant{}).Where("id = ?", *item.VariantID).Update("quantity", gorm.Expr("quantity + ?", item.Quantity)).Error
 	}
-
-	newQuantity := product.Quantity + quantityChange
-	if newQuantity < 0 {
-		return errors.New("not enough stock available for product ID " + fmt.Sprint(productID))
+	if item.VariantName != "" {
+		return nil
 	}
-
-	return tx.Model(&models.Product{}).Where("id = ?", productID).Update("quantity", newQuantity).Error
+	return tx.Model(&models.Product{}).Where("id = ?", item.ProductID).Update("quantity", gorm.Expr("quantity + ?", item.Quantity)).Error
 }
 
-func adjustInventory(tx *gorm.DB, productID uint, quantity int) error {
-	return tx.Model(&models.Product{}).Where("id = ?", productID).Update("quantity", gorm.Expr("quantity + ?", quantity)).Error
-}
-
-func (db *orderRepository) DeleteOrder(userID uint) error {
-	return db.connection.Transaction(func(tx *gorm.DB) error {
-		var order models.Order
-		if err := tx.Where("user_id = ? AND (order_status = ? OR order_status = ?)", userID, models.PENDING, models.ACCEPTED).First(&order).Error; err != nil {
-			return err
-		}
-
-		var orderItems []models.OrderItems
-		if err := tx.Where("order_id = ?", order.ID).Find(&orderItems).Error; err != nil {
-			return err
+func (db *orderRepository) SetOrderDiscount(orderID uint, percent int) (models.Order, error) {
+	var order models.Order
+	err := db.connection.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Order{}).
+			Where("id = ? AND order_status NOT IN ?", orderID, []models.OrderStatus{models.CONFIRMED, models.CANCELED}).
+			Update("discount_percent", percent)
+		if result.Error != nil {
+			return result.Error
 		}
-
-		for _, item := range orderItems {
-			if err := adjustInventory(tx, item.ProductID, item.Quantity); err != nil {
+		if result.RowsAffected == 0 {
+			if err := tx.First(&order, orderID).Error; err != nil {
 				return err
 			}
+			return ErrOrderClosed
 		}
-
-		if err := tx.Where("order_id = ?", order.ID).Delete(&models.OrderItems{}).Error; err != nil {
-			return err
-		}
-
-		if err := tx.Delete(&order).Error; err != nil {
+		if err := updateTotals(tx, orderID); err != nil {
 			return err
 		}
-
-		return nil
+		return tx.First(&order, orderID).Error
 	})
+	return order, err
 }
 
-func (db *orderRepository) DeleteOrderItem(userID uint, orderItemID uint) error {
-	return db.connection.Transaction(func(tx *gorm.DB) error {
-		var orderItem models.OrderItems
-		result := tx.Joins("JOIN orders ON orders.id = order_items.order_id").
-			Where("order_items.id = ? AND orders.user_id = ?", orderItemID, userID).
-			First(&orderItem)
-		if result.Error != nil {
-			return result.Error
+// setTotals computes the totals of order from its items, its discount and
+// its VAT. Discounts and taxes are rounded half up to whole minor units.
+func setTotals(order *models.Order, items []models.OrderItems) error {
+	var subtotal money.Money
+	for _, item := range items {
+		line, err := item.Price.Mul(int64(item.Quantity))
+		if err != nil {
+			return err
 		}
-
-		if err := tx.Delete(&orderItem).Error; err != nil {
+		if subtotal, err = subtotal.Add(line); err != nil {
 			return err
 		}
+	}
 
-		if err := adjustInventory(tx, orderItem.ProductID, orderItem.Quantity); err != nil {
+	discount, err := subtotal.MulRatio(int64(order.DiscountPercent), 100, money.HalfUp)
+	if err != nil {
+		return err
+	}
+	net, err := subtotal.Sub(discount)
+	if err != nil {
+		return err
+	}
+	rate := int64(order.VATRate)
+	var tax, total money.Money
+	if order.VATIncluded {
+		// the VAT share of a price that contains it
+		if tax, err = net.MulRatio(rate, 10000+rate, money.HalfUp); err != nil {
+			return err
+		}
+		total = net
+	} else {
+		if tax, err = net.MulRatio(rate, 10000, money.HalfUp); err != nil {
 			return err
 		}
+		if total, err = net.Add(tax); err != nil {
+			return err
+		}
+	}
 
-		return nil
-	})
+	order.Subtotal, order.Discount, order.Tax, order.Total = subtotal, discount, tax, total
+	return nil
+}
+
+func updateTotals(tx *gorm.DB, orderID uint) error {
+	var order models.Order
+	if err := tx.First(&order, orderID).Error; err != nil {
+		return err
+	}
+	var items []models.OrderItems
+	if err := tx.Where("order_id = ?", orderID).Find(&items).Error; err != nil {
+		return err
+	}
+	if err := setTotals(&order, items); err != nil {
+		return err
+	}
+	return tx.Model(&models.Order{}).Where("id = ?", orderID).Updates(map[string]any{
+		"subtotal_amount": order.Subtotal.Amount, "subtotal_currency": order.Subtotal.Currency,
+		"discount_amount": order.Discount.Amount, "discount_currency": order.Discount.Currency,
+		"tax_amount": order.Tax.Amount, "tax_currency": order.Tax.Currency,
+		"total_amount": order.Total.Amount, "total_currency": order.Total.Currency,
+	}).Error
 }
@@ -1,49 +1,206 @@
 package repositories
 
 import (
+	"errors"
 	"go_final/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// ProductListSpec are the fields products can be sorted and filtered by.
+// in_stock is derived from the quantity, or from the quantities of the
+// variants when the product has any.
+var ProductListSpec = ListSpec[models.Product]{Fields: map[string]Field[models.Product]{
+	"id":         {Column: "id", Kind: IntField, Value: func(p models.Product) any { return p.ID }},
+	"name":       {Column: "name", Kind: StringField, Value: func(p models.Product) any { return p.Name }},
+	"price":      {Column: "price_amount", Kind: IntField, Value: func(p models.Product) any { return p.Price.Amount }},
+	"quantity":   {Column: "quantity", Kind: IntField, Value: func(p models.Product) any { return p.Quantity }},
+	"in_stock":   {Column: inStock, Kind: BoolField, Value: func(p models.Product) any { return productInStock(p) }},
+	"created_at": {Column: "created_at", Kind: TimeField, Value: func(p models.Product) any { return p.CreatedAt }},
+	"category": {
+		Column: "id IN (SELECT product_id FROM product_categories WHERE category_id = ?)",
+		Kind:   IntField,
+		Values: func(p models.Product) []any {
+			ids := make([]any, len(p.Categories))
+			for i, category := range p.Categories {
+				ids[i] = category.ID
+			}
+			return ids
+		},
+	},
+	"tag": {
+		Column: "id IN (SELECT product_id FROM product_tags JOIN tags ON tags.id = product_tags.tag_id WHERE tags.name = ?)",
+		Kind:   StringField,
+		Values: func(p models.Product) []any {
+			names := make([]any, len(p.Tags))
+			for i, tag := range p.Tags {
+				names[i] = tag.Name
+			}
+			return names
+		},
+	},
+}}
+
+const inStock = `CASE WHEN EXISTS (SELECT 1 FROM product_variants WHERE product_variants.product_id = products.id)
+	THEN EXISTS (SELECT 1 FROM product_variants WHERE product_variants.product_id = products.id AND product_variants.quantity > 0)
+	ELSE quantity > 0 END`
+
+func productInStock(product models.Product) bool {
+	if len(product.Variants) == 0 {
+		return product.Quantity > 0
+	}
+	for _, variant := range product.Variants {
+		if variant.Quantity > 0 {
+			return true
+		}
+	}
+	return false
+}
+
 type ProductRepository interface {
 	Getproduct(int) (models.Product, error)
-	GetAllproduct() ([]models.Product, error)
+	GetAllproduct(ListQuery) (Page[models.Product], error)
 	AddProduct(models.Product) (models.Product, error)
 	UpdateProduct(models.Product) (models.Product, error)
 	DeleteProduct(models.Product) (models.Product, error)
+	SetProductImage(models.ProductImage) (*models.ProductImage, error)
+	DeleteProductImage(uint) (models.ProductImage, error)
+	AddVariant(models.ProductVariant) (models.ProductVariant, error)
+	UpdateVariant(models.ProductVariant) (models.ProductVariant, error)
+	DeleteVariant(productID, variantID uint) error
+	AddModifierGroup(models.ModifierGroup) (models.ModifierGroup, error)
+	UpdateModifierGroup(models.ModifierGroup) (models.ModifierGroup, error)
+	DeleteModifierGroup(productID, groupID uint) error
 }
 
 type productRepository struct {
 	connection *gorm.DB
 }
 
-func NewProductRepository() ProductRepository {
+func NewProductRepository(db *gorm.DB) ProductRepository {
 	return &productRepository{
-		connection: DB(),
+		connection: db,
 	}
 }
 
 func (db *productRepository) Getproduct(id int) (product models.Product, err error) {
-	return product, db.connection.First(&product, id).Error
+	return product, withLinks(db.connection).First(&product, id).Error
 }
 
-func (db *productRepository) GetAllproduct() (products []models.Product, err error) {
-	return products, db.connection.Find(&products).Error
+func (db *productRepository) GetAllproduct(query ListQuery) (Page[models.Product], error) {
+	return paginate(withLinks(db.connection).Model(&models.Product{}), ProductListSpec, query)
 }
 
 func (db *productRepository) AddProduct(product models.Product) (models.Product, error) {
-	return product, db.connection.Create(&product).Error
+	err := db.connection.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Create(&product).Error; err != nil {
+			return err
+		}
+		return replaceLinks(tx, &product)
+	})
+	return product, err
 }
 
 func (db *productRepository) UpdateProduct(product models.Product) (models.Product, error) {
 	if err := db.connection.First(&models.Product{}, product.ID).Error; err != nil {
 		return product, err
 	}
-	return product, db.connection.Model(&product).Updates(&product).Error
+	err := db.connection.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&product).Omit(clause.Associations).Updates(&product).Error; err != nil {
+			return err
+		}
+		return replaceLinks(tx, &product)
+	})
+	return product, err
+}
+
+// SetProductImage replaces the image of a product and returns the previous
+// one, if any, so that the caller can delete its files.
+func (db *productRepository) SetProductImage(image models.ProductImage) (*models.ProductImage, error) {
+	var previous *models.ProductImage
+	err := db.connection.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&models.Product{}, image.ProductID).Error; err != nil {
+			return err
+		}
+		var existing models.ProductImage
+		err := tx.First(&existing, image.ProductID).Error
+		switch {
+		case err == nil:
+			previous = &existing
+			if err := tx.Delete(&existing).Error; err != nil {
+				return err
+			}
+		case !errors.Is(err, gorm.ErrRecordNotFound):
+			return err
+		}
+		return tx.Create(&image).Error
+	})
+	return previous, err
+}
+
+func (db *productRepository) DeleteProductImage(productID uint) (models.ProductImage, error) {
+	var image models.ProductImage
+	err := db.connection.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&image, productID).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&image).Error
+	})
+	return image, err
+}
+
+func withLinks(db *gorm.DB) *gorm.DB {
+	return withOptions(db).Preload("Image").Preload("Categories", menuOrder).Preload("Tags", orderByName)
+}
+
+// replaceLinks links product to the categories and tags in CategoryIDs and
+// TagIDs, when given, and reloads it with its links.
+func replaceLinks(tx *gorm.DB, product *models.Product) error {
+	if product.CategoryIDs != nil {
+		categories := []models.Category{}
+		if len(product.CategoryIDs) > 0 {
+			if err := tx.Find(&categories, product.CategoryIDs).Error; err != nil {
+				return err
+			}
+		}
+		if len(categories) != countUnique(product.CategoryIDs) {
+			return ErrUnknownCategory
+		}
+		if err := tx.Model(product).Association("Categories").Replace(categories); err != nil {
+			return err
+		}
+	}
+	if product.TagIDs != nil {
+		tags := []models.Tag{}
+		if len(product.TagIDs) > 0 {
+			if err := tx.Find(&tags, product.TagIDs).Error; err != nil {
+				return err
+			}
+		}
+		if len(tags) != countUnique(product.TagIDs) {
+			return ErrUnknownTag
+		}
+		if err := tx.Model(product).Association("Tags").Replace(tags); err != nil {
+			return err
+		}
+	}
+
+	product.CategoryIDs, product.TagIDs = nil, nil
+	return withLinks(tx).First(product, product.ID).Error
+}
+
+func countUnique(ids []uint) int {
+	seen := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		seen[id] = true
+	}
+	return len(seen)
 }
 
+// DeleteProduct returns the deleted product with its image, whose files are
+// left to the caller.
 func (db *productRepository) DeleteProduct(product models.Product) (models.Product, error) {
-	if err := db.connection.First(&product, product.ID).Error; err != nil {
+	if err := withLinks(db.connection).First(&product, product.ID).Error; err != nil {
 		return product, err
 	}
 	return product, db.connection.Unscoped().Delete(&product).Error
@@ -0,0 +1,96 @@
+package repositories
+
+import (
+	"go_final/config"
+
+	"gorm.io/gorm"
+)
+
+const (
+	PostgresDriver = "postgres"
+	SQLiteDriver   = "sqlite"
+	MemoryDriver   = "memory"
+)
+
+// Store groups the repositories of a single storage backend so that all of
+// them share one connection (or one in-memory dataset).
+type Store interface {
+	Users() UserRepository
+	Products() ProductRepository
+	Orders() OrderRepository
+	Carts() CartRepository
+	Tokens() TokenRepository
+	LoginThrottles() LoginThrottleRepository
+	MFA() MFARepository
+	APIKeys() APIKeyRepository
+	Catalog() CatalogRepository
+	Close() error
+}
+
+// SQLStore is implemented by stores backed by a SQL database, it gives
+// access to the underlying connection for migrations.
+type SQLStore interface {
+	Store
+	DB() *gorm.DB
+}
+
+// NewStore opens the storage backend selected by cfg.Driver. Orders are
+// priced with pricing.
+func NewStore(cfg config.DatabaseConfig, pricing config.PricingConfig) (Store, error) {
+	if cfg.Driver == MemoryDriver {
+		return NewMemoryStore(pricing), nil
+	}
+
+	db, err := openDB(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return NewGormStore(db, pricing), nil
+}
+
+type gormStore struct {
+	db       *gorm.DB
+	users    UserRepository
+	products ProductRepository
+	orders   OrderRepository
+	carts    CartRepository
+	tokens   TokenRepository
+	throttle LoginThrottleRepository
+	mfa      MFARepository
+	apiKeys  APIKeyRepository
+	catalog  CatalogRepository
+}
+
+func NewGormStore(db *gorm.DB, pricing config.PricingConfig) Store {
+	return &gormStore{
+		db:       db,
+		users:    NewUserRepository(db),
+		products: NewProductRepository(db),
+		orders:   NewOrderRepository(db),
+		carts:    NewCartRepository(db, pricing),
+		tokens:   NewTokenRepository(db),
+		throttle: NewLoginThrottleRepository(db),
+		mfa:      NewMFARepository(db),
+		apiKeys:  NewAPIKeyRepository(db),
+		catalog:  NewCatalogRepository(db),
+	}
+}
+
+func (s *gormStore) DB() *gorm.DB                            { return s.db }
+func (s *gormStore) Users() UserRepository                   { return s.users }
+func (s *gormStore) Products() ProductRepository             { return s.products }
+func (s *gormStore) Orders() OrderRepository                 { return s.orders }
+func (s *gormStore) Carts() CartRepository                   { return s.carts }
+func (s *gormStore) Tokens() TokenRepository                 { return s.tokens }
+func (s *gormStore) LoginThrottles() LoginThrottleRepository { return s.throttle }
+func (s *gormStore) MFA() MFARepository                      { return s.mfa }
+func (s *gormStore) APIKeys() APIKeyRepository               { return s.apiKeys }
+func (s *gormStore) Catalog() CatalogRepository              { return s.catalog }
+
+func (s *gormStore) Close() error {
+	sqlDB, err := s.db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
@@ -0,0 +1,162 @@
+package repositories
+
+import (
+	"errors"
+	"go_final/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrTokenRevoked     = errors.New("refresh token has been revoked")
+	ErrUserTokenInvalid = errors.New("token is invalid, expired or already used")
+)
+
+type TokenRepository interface {
+	CreateRefreshToken(models.RefreshToken) (models.RefreshToken, error)
+	GetRefreshToken(string) (models.RefreshToken, error)
+	RotateRefreshToken(uint, models.RefreshToken) (models.RefreshToken, error)
+	RevokeRefreshTokenFamily(string) error
+	RevokeAccessToken(string, time.Time) error
+	RevokeUserTokens(uint) error
+	IsAccessTokenRevoked(string, uint, time.Time) (bool, error)
+	CreateUserToken(models.UserToken) (models.UserToken, error)
+	ConsumeUserToken(models.UserTokenPurpose, string) (models.UserToken, error)
+}
+
+type tokenRepository struct {
+	connection *gorm.DB
+}
+
+func NewTokenRepository(db *gorm.DB) TokenRepository {
+	return &tokenRepository{
+		connection: db,
+	}
+}
+
+func (db *tokenRepository) CreateRefreshToken(token models.RefreshToken) (models.RefreshToken, error) {
+	return token, db.connection.Create(&token).Error
+}
+
+func (db *tokenRepository) GetRefreshToken(hash string) (token models.RefreshToken, err error) {
+	return token, db.connection.First(&token, "token_hash = ?", hash).Error
+}
+
+// RotateRefreshToken revokes the token with oldID and stores next in its
+// place. It fails with ErrTokenRevoked if the old token was already used.
+func (db *tokenRepository) RotateRefreshToken(oldID uint, next models.RefreshToken) (models.RefreshToken, error) {
+	err := db.connection.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.RefreshToken{}).
+			Where("id = ? AND revoked_at IS NULL", oldID).
+			Update("revoked_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrTokenRevoked
+		}
+		return tx.Create(&next).Error
+	})
+	return next, err
+}
+
+func (db *tokenRepository) RevokeRefreshTokenFamily(familyID string) error {
+	return db.connection.Model(&models.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (db *tokenRepository) RevokeAccessToken(jti string, expiresAt time.Time) error {
+	return db.connection.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("expires_at < ?", time.Now()).Delete(&models.RevokedToken{}).Error; err != nil {
+			return err
+		}
+		return tx.Save(&models.RevokedToken{JTI: jti, ExpiresAt: expiresAt}).Error
+	})
+}
+
+func (db *tokenRepository) RevokeUserTokens(userID uint) error {
+	return db.connection.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		if err := tx.Save(&models.UserTokenCutoff{UserID: userID, RevokedBefore: now}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.RefreshToken{}).
+			Where("user_id = ? AND revoked_at IS NULL", userID).
+			Update("revoked_at", now).Error
+	})
+}
+
+func (db *tokenRepository) IsAccessTokenRevoked(jti string, userID uint, issuedAt time.Time) (bool, error) {
+	var count int64
+	if err := db.connection.Model(&models.RevokedToken{}).Where("jti = ?", jti).Count(&count).Error; err != nil {
+		return false, err
+	}
+	if count > 0 {
+		return true, nil
+	}
+
+	var cutoff models.UserTokenCutoff
+	err := db.connection.Where("user_id = ?", userID).Limit(1).Find(&cutoff).Error
+	if err != nil {
+		return false, err
+	}
+	return isBeforeCutoff(issuedAt, cutoff.RevokedBefore), nil
+}
+
+// isBeforeCutoff compares at millisecond precision, the precision of the
+// issue time of tokens. Tokens issued in the same millisecond as the cutoff,
+// or in the same second for tokens that only carry iat, are treated as
+// revoked.
+func isBeforeCutoff(issuedAt, cutoff time.Time) bool {
+	return !cutoff.IsZero() && !issuedAt.After(cutoff.Truncate(time.Millisecond))
+}
+
+// CreateUserToken stores token and invalidates the unused tokens the user
+// was sent earlier for the same purpose, so only the latest link works.
+func (db *tokenRepository) CreateUserToken(token models.UserToken) (models.UserToken, error) {
+	err := db.connection.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.UserToken{}).
+			Where("user_id = ? AND purpose = ? AND used_at IS NULL", token.UserID, token.Purpose).
+			Update("used_at", time.Now()).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(&token).Error
+	})
+	return token, err
+}
+
+// ConsumeUserToken marks the token with the given hash as used. It fails
+// with ErrUserTokenInvalid if there is no such token for purpose or it has
+// expired or been used before.
+func (db *tokenRepository) ConsumeUserToken(purpose models.UserTokenPurpose, hash string) (models.UserToken, error) {
+	var token models.UserToken
+	err := db.connection.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("token_hash = ? AND purpose = ?", hash, purpose).First(&token).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrUserTokenInvalid
+		}
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		if now.After(token.ExpiresAt) {
+			return ErrUserTokenInvalid
+		}
+
+		result := tx.Model(&models.UserToken{}).
+			Where("id = ? AND used_at IS NULL", token.ID).
+			Update("used_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrUserTokenInvalid
+		}
+		token.UsedAt = &now
+		return nil
+	})
+	return token, err
+}
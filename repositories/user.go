@@ -1,26 +1,53 @@
 package repositories
 
 import (
+	"errors"
+	"fmt"
 	"go_final/models"
 	"gorm.io/gorm"
+	"time"
 )
 
+var ErrUserAnonymized = errors.New("user has been anonymized")
+
+// anonymizedName replaces the name of anonymized users, their email becomes
+// an address under the reserved .invalid domain.
+const anonymizedName = "Anonymized user"
+
+func anonymizedEmail(id uint) string {
+	return fmt.Sprintf("anonymized-%d@example.invalid", id)
+}
+
+// UserListSpec are the fields users can be sorted and filtered by.
+var UserListSpec = ListSpec[models.APIUser]{Fields: map[string]Field[models.APIUser]{
+	"id":         {Column: "id", Kind: IntField, Value: func(u models.APIUser) any { return u.ID }},
+	"name":       {Column: "name", Kind: StringField, Value: func(u models.APIUser) any { return u.Name }},
+	"email":      {Column: "email", Kind: StringField, Value: func(u models.APIUser) any { return u.Email }},
+	"role":       {Column: "role", Kind: StringField, Value: func(u models.APIUser) any { return string(u.Role) }},
+	"created_at": {Column: "created_at", Kind: TimeField, Value: func(u models.APIUser) any { return u.CreatedAt }},
+}}
+
 type UserRepository interface {
 	CreateUser(models.User) (models.User, error)
 	GetUser(int) (models.APIUser, error)
 	GetByEmail(string) (models.User, error)
-	GetAllUsers() ([]models.APIUser, error)
+	// GetByID is GetUser with the full record, password hash included.
+	GetByID(uint) (models.User, error)
+	GetAllUsers(ListQuery) (Page[models.APIUser], error)
 	UpdateUser(models.User) (models.User, error)
 	DeleteUser(models.User) (models.User, error)
+	GetDeletedUsers() ([]models.APIUser, error)
+	RestoreUser(uint) (models.APIUser, error)
+	AnonymizeUser(uint) (models.APIUser, error)
 }
 
 type userRepository struct {
 	connection *gorm.DB
 }
 
-func NewUserRepository() UserRepository {
+func NewUserRepository(db *gorm.DB) UserRepository {
 	return &userRepository{
-		connection: DB(),
+		connection: db,
 	}
 }
 
@@ -32,8 +59,12 @@ func (db *userRepository) GetByEmail(email string) (user models.User, err error)
 	return user, db.connection.First(&user, "email=?", email).Error
 }
 
-func (db *userRepository) GetAllUsers() (users []models.APIUser, err error) {
-	return users, db.connection.Model(&models.User{}).Find(&users).Error
+func (db *userRepository) GetByID(id uint) (user models.User, err error) {
+	return user, db.connection.First(&user, id).Error
+}
+
+func (db *userRepository) GetAllUsers(query ListQuery) (Page[models.APIUser], error) {
+	return paginate(db.connection.Model(&models.User{}), UserListSpec, query)
 }
 
 func (db *userRepository) CreateUser(user models.User) (models.User, error) {
@@ -51,5 +82,60 @@ func (db *userRepository) DeleteUser(user models.User) (models.User, error) {
 	if err := db.connection.First(&user, user.ID).Error; err != nil {
 		return user, err
 	}
-	return user, db.connection.Unscoped().Delete(&user).Error
+	return user, db.connection.Delete(&user).Error
+}
+
+func (db *userRepository) GetDeletedUsers() (users []models.APIUser, err error) {
+	return users, db.connection.Unscoped().Model(&models.User{}).
+		Where("deleted_at IS NOT NULL").Order("id").Find(&users).Error
+}
+
+// RestoreUser undoes DeleteUser. It fails with gorm.ErrRecordNotFound if the
+// user is not deleted, and with ErrUserAnonymized if there is nothing left to
+// restore.
+func (db *userRepository) RestoreUser(id uint) (user models.APIUser, err error) {
+	err = db.connection.Transaction(func(tx *gorm.DB) error {
+		var deleted models.User
+		if err := tx.Unscoped().Where("deleted_at IS NOT NULL").First(&deleted, id).Error; err != nil {
+			return err
+		}
+		if deleted.AnonymizedAt != nil {
+			return ErrUserAnonymized
+		}
+		if err := tx.Unscoped().Model(&deleted).Update("deleted_at", nil).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.User{}).First(&user, id).Error
+	})
+	return user, err
+}
+
+// AnonymizeUser scrubs the personal data of a user, deleted or not, and
+// deletes the user if they were not yet. Their orders are kept, their cart
+// and everything the user could sign in with go.
+func (db *userRepository) AnonymizeUser(id uint) (user models.APIUser, err error) {
+	err = db.connection.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().First(&models.User{}, id).Error; err != nil {
+			return err
+		}
+		now := time.Now()
+		err := tx.Unscoped().Model(&models.User{}).Where("id = ?", id).Updates(map[string]interface{}{
+			"name":              anonymizedName,
+			"email":             anonymizedEmail(id),
+			"password":          "",
+			"email_verified_at": nil,
+			"anonymized_at":     now,
+			"deleted_at":        gorm.Expr("COALESCE(deleted_at, ?)", now),
+		}).Error
+		if err != nil {
+			return err
+		}
+		for _, table := range []interface{}{&models.UserToken{}, &models.RefreshToken{}, &models.RecoveryCode{}, &models.UserMFA{}, &models.CartItem{}} {
+			if err := tx.Unscoped().Where("user_id = ?", id).Delete(table).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Unscoped().Model(&models.User{}).First(&user, id).Error
+	})
+	return user, err
 }
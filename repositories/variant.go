@@ -0,0 +1,194 @@
+package repositories
+
+import (
+	"fmt"
+	"go_final/models"
+	"go_final/money"
+	"sort"
+
+	"gorm.io/gorm"
+)
+
+func (db *productRepository) AddVariant(variant models.ProductVariant) (models.ProductVariant, error) {
+	if err := db.connection.First(&models.Product{}, variant.ProductID).Error; err != nil {
+		return variant, err
+	}
+	return variant, db.connection.Create(&variant).Error
+}
+
+func (db *productRepository) UpdateVariant(variant models.ProductVariant) (models.ProductVariant, error) {
+	var existing models.ProductVariant
+	if err := db.connection.Where("product_id = ?", variant.ProductID).First(&existing, variant.ID).Error; err != nil {
+		return variant, err
+	}
+	variant.CreatedAt = existing.CreatedAt
+	return variant, db.connection.Save(&variant).Error
+}
+
+func (db *productRepository) DeleteVariant(productID, variantID uint) error {
+	result := db.connection.Unscoped().Where("product_id = ?", productID).Delete(&models.ProductVariant{}, variantID)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (db *productRepository) AddModifierGroup(group models.ModifierGroup) (models.ModifierGroup, error) {
+	if err := db.connection.First(&models.Product{}, group.ProductID).Error; err != nil {
+		return group, err
+	}
+	return group, db.connection.Create(&group).Error
+}
+
+// UpdateModifierGroup replaces the group and all of its options. Orders keep
+// the names and prices of the options they picked, cart items that picked
+// one are reported until they are changed.
+func (db *productRepository) UpdateModifierGroup(group models.ModifierGroup) (models.ModifierGroup, error) {
+	err := db.connection.Transaction(func(tx *gorm.DB) error {
+		var existing models.ModifierGroup
+		if err := tx.Where("product_id = ?", group.ProductID).First(&existing, group.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("group_id = ?", group.ID).Delete(&models.ModifierOption{}).Error; err != nil {
+			return err
+		}
+		group.CreatedAt = existing.CreatedAt
+		for i := range group.Options {
+			group.Options[i].ID = 0
+		}
+		return tx.Save(&group).Error
+	})
+	return group, err
+}
+
+func (db *productRepository) DeleteModifierGroup(productID, groupID uint) error {
+	result := db.connection.Unscoped().Where("product_id = ?", productID).Delete(&models.ModifierGroup{}, groupID)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// withOptions preloads the variants and modifier groups of products.
+func withOptions(db *gorm.DB) *gorm.DB {
+	return db.Preload("Variants", byPosition).Preload("ModifierGroups", byPosition).Preload("ModifierGroups.Options", byPosition)
+}
+
+func byPosition(db *gorm.DB) *gorm.DB {
+	return db.Order("position, id")
+}
+
+// pricedItem is a cart item checked against its product.
+type pricedItem struct {
+	product string
+	// name and stock are those of the variant, or of the product without
+	// variants
+	name      string
+	stock     int
+	variant   *models.ProductVariant
+	modifiers []models.OrderItemModifier
+	// price is the unit price of the item
+	price money.Money
+}
+
+func (item pricedItem) variantID() *uint {
+	if item.variant == nil {
+		return nil
+	}
+	return &item.variant.ID
+}
+
+func (item pricedItem) variantName() string {
+	if item.variant == nil {
+		return ""
+	}
+	return item.variant.Name
+}
+
+// priceItem checks the variant and modifiers picked by item against product,
+// which must have its variants and modifier groups loaded, and computes the
+// unit price of the item.
+func priceItem(product models.Product, item models.CartItemRequest) (pricedItem, error) {
+	if item.Quantity <= 0 {
+		return pricedItem{}, fmt.Errorf("quantity of %s must be positive", product.Name)
+	}
+
+	priced := pricedItem{product: product.Name, name: product.Name, stock: product.Quantity, price: product.Price}
+	if len(product.Variants) > 0 {
+		for i := range product.Variants {
+			if product.Variants[i].ID == item.VariantID {
+				priced.variant = &product.Variants[i]
+			}
+		}
+		if priced.variant == nil {
+			return pricedItem{}, fmt.Errorf("a variant of %s must be chosen", product.Name)
+		}
+		priced.name = product.Name + " (" + priced.variant.Name + ")"
+		priced.stock = priced.variant.Quantity
+		priced.price = priced.variant.Price
+	} else if item.VariantID != 0 {
+		return pricedItem{}, fmt.Errorf("%s has no variants", product.Name)
+	}
+
+	selected := make(map[uint]bool, len(item.ModifierIDs))
+	for _, id := range item.ModifierIDs {
+		if selected[id] {
+			return pricedItem{}, fmt.Errorf("modifier %d is chosen twice for %s", id, product.Name)
+		}
+		selected[id] = true
+	}
+	for _, group := range product.ModifierGroups {
+		count := 0
+		for _, option := range group.Options {
+			if !selected[option.ID] {
+				continue
+			}
+			delete(selected, option.ID)
+			count++
+			optionID := option.ID
+			priced.modifiers = append(priced.modifiers, models.OrderItemModifier{
+				ModifierOptionID: &optionID,
+				Name:             option.Name,
+				PriceDelta:       option.PriceDelta,
+			})
+			var err error
+			if priced.price, err = priced.price.Add(option.PriceDelta); err != nil {
+				return pricedItem{}, fmt.Errorf("price of %s: %w", product.Name, err)
+			}
+		}
+		if count < group.MinSelections || count > group.MaxSelections {
+			return pricedItem{}, fmt.Errorf("choose between %d and %d of %s for %s", group.MinSelections, group.MaxSelections, group.Name, product.Name)
+		}
+	}
+	if len(selected) > 0 {
+		return pricedItem{}, fmt.Errorf("unknown modifier for %s", product.Name)
+	}
+
+	if priced.price.IsNegative() {
+		return pricedItem{}, fmt.Errorf("price of %s cannot be negative", product.Name)
+	}
+	return priced, nil
+}
+
+// sameIDs reports whether a and b hold the same ids, in any order.
+func sameIDs(a, b []uint) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	a = append([]uint(nil), a...)
+	b = append([]uint(nil), b...)
+	sort.Slice(a, func(i, j int) bool { return a[i] < a[j] })
+	sort.Slice(b, func(i, j int) bool { return b[i] < b[j] })
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -1,68 +1,215 @@
 package route
 
 import (
+	"context"
+	"errors"
 	"github.com/gin-gonic/gin"
+	"go_final/app"
+	"go_final/auth"
 	"go_final/handlers"
 	"go_final/middleware"
+	"log"
 	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 )
 
-func RunAPI(address string) error {
+func NewRouter(c *app.Container) *gin.Engine {
 
-	userHandler := handlers.NewUserHandler()
-	productHandler := handlers.NewProductHandler()
-	orderHandler := handlers.NewOrderHandler()
+	userHandler := c.UserHandler
+	productHandler := c.ProductHandler
+	orderHandler := c.OrderHandler
+	cartHandler := c.CartHandler
+	apiKeyHandler := c.APIKeyHandler
+	privacyHandler := c.PrivacyHandler
+	catalogHandler := c.CatalogHandler
 
+	if c.Config.LogLevel != "debug" {
+		gin.SetMode(gin.ReleaseMode)
+	}
 	r := gin.Default()
+	// the list is validated with the rest of the configuration
+	_ = r.SetTrustedProxies(c.Config.Server.TrustedProxies)
+	r.Use(c.CORSMiddleware)
 
 	r.GET("/", func(ctx *gin.Context) {
 		ctx.String(http.StatusOK, "Welcome to SDU Canteen!")
 	})
+	r.GET("/.well-known/jwks.json", handlers.JWKS(c.Keys))
+	r.GET(mediaPath(c.Config.Media.BaseURL)+"/*key", handlers.Media(c.Blobs))
+	r.GET("/healthz", c.HealthHandler.Live)
+	r.GET("/readyz", c.HealthHandler.Ready)
 
 	apiRoutes := r.Group("/api")
 	userRoutes := apiRoutes.Group("/user")
 	{
 		userRoutes.POST("/register", userHandler.CreateUser)
 		userRoutes.POST("/signin", userHandler.SignInUser)
+		userRoutes.POST("/signin/mfa", userHandler.SignInMFA)
+		userRoutes.POST("/refresh", userHandler.RefreshToken)
+		userRoutes.POST("/verify", userHandler.VerifyEmail)
+		userRoutes.POST("/confirm-email", userHandler.ConfirmEmailChange)
+		userRoutes.POST("/resend-verification", userHandler.ResendVerification)
+		userRoutes.POST("/forgot-password", userHandler.ForgotPassword)
+		userRoutes.POST("/reset-password", userHandler.ResetPassword)
+		userRoutes.POST("/logout", c.UserAuthMiddleware, userHandler.SignOutUser)
+	}
+
+	// reachable without a second factor, so users of roles that require one
+	// can set it up
+	mfaRoutes := userRoutes.Group("/mfa", c.UserAuthMiddleware)
+	{
+		mfaRoutes.POST("/totp", userHandler.EnrollTOTP)
+		mfaRoutes.POST("/totp/confirm", userHandler.ConfirmTOTP)
+		mfaRoutes.DELETE("/totp", userHandler.DisableTOTP)
+		mfaRoutes.POST("/recovery-codes", userHandler.RegenerateRecoveryCodes)
+	}
+
+	apiRoutes.GET("/roles", c.AuthMiddleware, c.MFAMiddleware, middleware.RequirePermission(auth.UsersRoles), userHandler.GetRoles)
+
+	// the caller's own account, only for users since API keys have none
+	meRoutes := apiRoutes.Group("/users/me", c.UserAuthMiddleware, c.MFAMiddleware)
+	{
+		meRoutes.GET("", userHandler.GetMe)
+		meRoutes.PATCH("", userHandler.UpdateMe)
+		meRoutes.DELETE("", userHandler.DeleteMe)
+		meRoutes.GET("/export", privacyHandler.ExportMe)
 	}
 
-	userSecuredRoutes := apiRoutes.Group("/users", middleware.AuthorizeJWT())
+	userSecuredRoutes := apiRoutes.Group("/users", c.AuthMiddleware, c.MFAMiddleware)
 	{
-		userSecuredRoutes.GET("/", userHandler.GetAllUsers)
-		userSecuredRoutes.GET("/:user_id", userHandler.GetUser)
-		adminRoutes := userSecuredRoutes.Group("/", middleware.CheckAdmin())
-		{
-			adminRoutes.PUT("/:user_id", userHandler.UpdateUser)
-			adminRoutes.DELETE("/:user_id", userHandler.DeleteUser)
-		}
+		userSecuredRoutes.GET("/", middleware.RequirePermission(auth.UsersRead), userHandler.GetAllUsers)
+		userSecuredRoutes.GET("/deleted", middleware.RequirePermission(auth.UsersRead), userHandler.GetDeletedUsers)
+		userSecuredRoutes.GET("/:user_id", middleware.Authorize(auth.ActionRead, middleware.UserParam), userHandler.GetUser)
+		userSecuredRoutes.PUT("/:user_id", middleware.Authorize(auth.ActionUpdate, middleware.UserParam), userHandler.UpdateUser)
+		userSecuredRoutes.PUT("/:user_id/role", middleware.RequirePermission(auth.UsersRoles), userHandler.UpdateUserRole)
+		userSecuredRoutes.DELETE("/:user_id/lockout", middleware.RequirePermission(auth.UsersWrite), userHandler.UnlockUser)
+		userSecuredRoutes.DELETE("/:user_id/mfa", middleware.RequirePermission(auth.UsersWrite), userHandler.ResetUserMFA)
+		userSecuredRoutes.DELETE("/:user_id", middleware.Authorize(auth.ActionDelete, middleware.UserParam), userHandler.DeleteUser)
+		userSecuredRoutes.POST("/:user_id/restore", middleware.RequirePermission(auth.UsersDelete), userHandler.RestoreUser)
+		userSecuredRoutes.POST("/:user_id/anonymize", middleware.RequirePermission(auth.UsersDelete), privacyHandler.AnonymizeUser)
 	}
 
-	productRoutes := apiRoutes.Group("/products", middleware.AuthorizeJWT())
+	productRoutes := apiRoutes.Group("/products", c.AuthMiddleware, c.MFAMiddleware)
 	{
-		productRoutes.GET("/", productHandler.GetAllProduct)
-		productRoutes.GET("/:product_id", productHandler.GetProduct)
-		adminRoutes := productRoutes.Group("/", middleware.CheckAdmin())
-		{
-			adminRoutes.POST("/", productHandler.CreateProduct)
-			adminRoutes.PUT("/:product_id", productHandler.UpdateProduct)
-			adminRoutes.DELETE("/:product_id", productHandler.DeleteProduct)
-		}
+		productRoutes.GET("/", middleware.Authorize(auth.ActionRead, middleware.AnyProduct), productHandler.GetAllProduct)
+		productRoutes.GET("/:product_id", middleware.Authorize(auth.ActionRead, middleware.AnyProduct), productHandler.GetProduct)
+		productRoutes.POST("/", middleware.Authorize(auth.ActionCreate, middleware.AnyProduct), productHandler.CreateProduct)
+		productRoutes.PUT("/:product_id", middleware.Authorize(auth.ActionUpdate, middleware.AnyProduct), productHandler.UpdateProduct)
+		productRoutes.DELETE("/:product_id", middleware.Authorize(auth.ActionDelete, middleware.AnyProduct), productHandler.DeleteProduct)
+		productRoutes.DELETE("/:product_id/image", middleware.Authorize(auth.ActionUpdate, middleware.AnyProduct), productHandler.DeleteProductImage)
+		productRoutes.POST("/:product_id/variants", middleware.Authorize(auth.ActionUpdate, middleware.AnyProduct), productHandler.CreateVariant)
+		productRoutes.PUT("/:product_id/variants/:variant_id", middleware.Authorize(auth.ActionUpdate, middleware.AnyProduct), productHandler.UpdateVariant)
+		productRoutes.DELETE("/:product_id/variants/:variant_id", middleware.Authorize(auth.ActionUpdate, middleware.AnyProduct), productHandler.DeleteVariant)
+		productRoutes.POST("/:product_id/modifier-groups", middleware.Authorize(auth.ActionUpdate, middleware.AnyProduct), productHandler.CreateModifierGroup)
+		productRoutes.PUT("/:product_id/modifier-groups/:group_id", middleware.Authorize(auth.ActionUpdate, middleware.AnyProduct), productHandler.UpdateModifierGroup)
+		productRoutes.DELETE("/:product_id/modifier-groups/:group_id", middleware.Authorize(auth.ActionUpdate, middleware.AnyProduct), productHandler.DeleteModifierGroup)
 	}
 
-	orderRoutes := apiRoutes.Group("/order", middleware.AuthorizeJWT())
+	apiRoutes.GET("/menu", c.AuthMiddleware, c.MFAMiddleware, middleware.RequirePermission(auth.ProductsRead), catalogHandler.GetMenu)
+
+	categoryRoutes := apiRoutes.Group("/categories", c.AuthMiddleware, c.MFAMiddleware)
+	{
+		categoryRoutes.GET("/", middleware.RequirePermission(auth.ProductsRead), catalogHandler.GetCategories)
+		categoryRoutes.GET("/:category_id", middleware.RequirePermission(auth.ProductsRead), catalogHandler.GetCategory)
+		categoryRoutes.POST("/", middleware.RequirePermission(auth.ProductsWrite), catalogHandler.CreateCategory)
+		categoryRoutes.PUT("/:category_id", middleware.RequirePermission(auth.ProductsWrite), catalogHandler.UpdateCategory)
+		categoryRoutes.DELETE("/:category_id", middleware.RequirePermission(auth.ProductsWrite), catalogHandler.DeleteCategory)
+	}
+
+	tagRoutes := apiRoutes.Group("/tags", c.AuthMiddleware, c.MFAMiddleware)
+	{
+		tagRoutes.GET("/", middleware.RequirePermission(auth.ProductsRead), catalogHandler.GetTags)
+		tagRoutes.POST("/", middleware.RequirePermission(auth.ProductsWrite), catalogHandler.CreateTag)
+		tagRoutes.PUT("/:tag_id", middleware.RequirePermission(auth.ProductsWrite), catalogHandler.UpdateTag)
+		tagRoutes.DELETE("/:tag_id", middleware.RequirePermission(auth.ProductsWrite), catalogHandler.DeleteTag)
+	}
+
+	// handlers of a specific order ask the policy engine once it is loaded
+	orderRoutes := apiRoutes.Group("/order", c.AuthMiddleware, c.MFAMiddleware)
 	{
-		orderRoutes.POST("/", orderHandler.OrderProducts)
 		orderRoutes.GET("/", orderHandler.GetOrders)
-		adminRoutes := orderRoutes.Group("/", middleware.CheckAdmin())
-		{
-			adminRoutes.GET("/:order_id", orderHandler.GetOrderByID)
-			adminRoutes.GET("/:order_id/order_items", orderHandler.GetOrderItems)
-			adminRoutes.PUT("/", orderHandler.UpdateOrder)
-			adminRoutes.PUT("/order_status/:order_id/:status", orderHandler.UpdateOrderStatus)
-			adminRoutes.DELETE("/", orderHandler.DeleteOrder)
-			adminRoutes.DELETE("/order_items/:order_item_id", orderHandler.DeleteOrderItem)
-		}
-	}
-
-	return r.Run(address)
+		orderRoutes.GET("/:order_id", orderHandler.GetOrderByID)
+		orderRoutes.GET("/:order_id/order_items", orderHandler.GetOrderItems)
+		orderRoutes.GET("/:order_id/receipt", orderHandler.GetReceipt)
+		orderRoutes.PUT("/:order_id/discount", orderHandler.SetDiscount)
+		orderRoutes.PUT("/order_status/:order_id/:status", orderHandler.UpdateOrderStatus)
+	}
+
+	// the cart is always the caller's own
+	cartRoutes := apiRoutes.Group("/cart", c.AuthMiddleware, c.MFAMiddleware, middleware.RequirePermission(auth.OrdersCreate))
+	{
+		cartRoutes.GET("/", cartHandler.GetCart)
+		cartRoutes.DELETE("/", cartHandler.ClearCart)
+		cartRoutes.POST("/items", cartHandler.AddItem)
+		cartRoutes.PUT("/items/:item_id", cartHandler.UpdateItem)
+		cartRoutes.DELETE("/items/:item_id", cartHandler.DeleteItem)
+		cartRoutes.POST("/checkout", cartHandler.Checkout)
+	}
+
+	apiKeyRoutes := apiRoutes.Group("/api-keys", c.AuthMiddleware, c.MFAMiddleware, middleware.RequirePermission(auth.APIKeysManage))
+	{
+		apiKeyRoutes.GET("/", apiKeyHandler.GetAPIKeys)
+		apiKeyRoutes.POST("/", apiKeyHandler.CreateAPIKey)
+		apiKeyRoutes.DELETE("/:api_key_id", apiKeyHandler.RevokeAPIKey)
+	}
+
+	return r
+}
+
+// mediaPath is the path the files of the blob store are served at, the path
+// of their base URL, which may also name a CDN in front of the server.
+func mediaPath(baseURL string) string {
+	if parsed, err := url.Parse(baseURL); err == nil {
+		baseURL = parsed.Path
+	}
+	return "/" + strings.Trim(baseURL, "/")
+}
+
+// RunAPI serves the API until SIGINT or SIGTERM is received. On a signal the
+// health state flips to draining, the server keeps serving for the drain
+// delay and then waits for in-flight requests before returning.
+func RunAPI(c *app.Container) error {
+	cfg := c.Config.Server
+	srv := &http.Server{
+		Addr:         cfg.Address,
+		Handler:      NewRouter(c),
+		ReadTimeout:  cfg.ReadTimeout.Duration,
+		WriteTimeout: cfg.WriteTimeout.Duration,
+		IdleTimeout:  cfg.IdleTimeout.Duration,
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- srv.ListenAndServe()
+	}()
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(quit)
+
+	select {
+	case err := <-serveErr:
+		return err
+	case sig := <-quit:
+		log.Printf("Received %s, draining", sig)
+	}
+
+	c.Health.SetDraining()
+	time.Sleep(cfg.DrainDelay.Duration)
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout.Duration)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		return err
+	}
+	if err := <-serveErr; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	log.Println("Server stopped")
+	return nil
 }
@@ -1,15 +0,0 @@
-#!/bin/sh
-
-set -e
-
-host="$1"
-shift
-cmd="$@"
-
-until PGPASSWORD=$POSTGRES_PASSWORD psql -h "$host" -U "postgres" -c '\q'; do
-  >&2 echo "Postgres is unavailable - sleeping"
-  sleep 3
-done
-
->&2 echo "Postgres is up - executing command"
-exec $cmd